@@ -0,0 +1,211 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// StdModule is the module name used for standard library packages in the
+// module graph.
+const StdModule = "std"
+
+// AggregateEdge is a caller to callee relationship between two packages or
+// modules, summarizing the function-level call edges between them.
+type AggregateEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// CallSites counts the distinct call instructions from From into To
+	CallSites int `json:"call_sites"`
+	// Callees are the distinct function IDs of To called from From
+	Callees []string `json:"callees"`
+}
+
+// UnusedDependency is an external dependency none of whose functions is
+// reachable from the entrypoints.
+type UnusedDependency struct {
+	Label      string `json:"label"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	ImportPath string `json:"import_path"`
+	// Imported is set when some package of the dependency is linked into
+	// the program, so its code is built but never called.
+	Imported bool `json:"imported"`
+}
+
+// ModuleResolver maps package import paths to the path of their module.
+type ModuleResolver struct {
+	modules  map[string]string
+	versions map[string]string
+	deps     []string
+}
+
+// NewModuleResolver resolves modules from the go.mod information of the
+// loaded packages and their dependencies, falling back to the import paths
+// of the external dependencies for packages loaded without module data.
+func NewModuleResolver(pkgs []*packages.Package, deps []Dependency) *ModuleResolver {
+	r := &ModuleResolver{modules: make(map[string]string), versions: make(map[string]string)}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Module != nil {
+			r.modules[pkg.PkgPath] = pkg.Module.Path
+			if pkg.Module.Version != "" {
+				r.versions[pkg.Module.Path] = pkg.Module.Version
+			}
+		}
+	})
+	for _, dep := range deps {
+		if !dep.Internal && dep.Path() != "" {
+			r.deps = append(r.deps, dep.Path())
+			if dep.Version != "" {
+				r.versions[dep.Path()] = dep.Version
+			}
+		}
+	}
+	// Match against the most specific import path first
+	sort.SliceStable(r.deps, func(i, j int) bool { return len(r.deps[i]) > len(r.deps[j]) })
+	return r
+}
+
+// Module returns the module of the package: its go.mod module, else the
+// external dependency containing it, else "std" for standard library
+// packages. It reports false for packages matching none of these, such as
+// packages loaded from export data without the dependencies of --deps.
+func (r *ModuleResolver) Module(pkgPath string) (string, bool) {
+	if module, ok := r.modules[pkgPath]; ok {
+		return module, true
+	}
+	for _, dep := range r.deps {
+		if pkgPath == dep || strings.HasPrefix(pkgPath, dep+"/") {
+			return dep, true
+		}
+	}
+	if isStandardImportPath(pkgPath) {
+		return StdModule, true
+	}
+	return "", false
+}
+
+// Unresolved returns the sorted packages among pkgPaths whose module is
+// unknown.
+func (r *ModuleResolver) Unresolved(pkgPaths []string) []string {
+	var unresolved []string
+	seen := make(map[string]bool)
+	for _, pkgPath := range pkgPaths {
+		if _, ok := r.Module(pkgPath); !ok && !seen[pkgPath] {
+			seen[pkgPath] = true
+			unresolved = append(unresolved, pkgPath)
+		}
+	}
+	sort.Strings(unresolved)
+	return unresolved
+}
+
+// Version returns the version of a module, or "" when it is unknown.
+func (r *ModuleResolver) Version(module string) string {
+	return r.versions[module]
+}
+
+// isStandardImportPath reports whether the import path has no domain in its
+// first element, as standard library packages do.
+func isStandardImportPath(pkgPath string) bool {
+	first := strings.SplitN(pkgPath, "/", 2)[0]
+	return pkgPath != "" && !strings.Contains(first, ".")
+}
+
+// edgePackages returns the packages of the callers and callees of the
+// result's call edges.
+func edgePackages(result *CallGraphResult) []string {
+	var pkgPaths []string
+	for _, edge := range result.CallEdges {
+		pkgPaths = append(pkgPaths, result.Functions[edge.Caller].Package, result.Functions[edge.Callee].Package)
+	}
+	return pkgPaths
+}
+
+// Aggregate groups the call edges of the result by the package or module of
+// their caller and callee, as given by key. Calls within one group are
+// dropped. Call sites are counted once per call instruction, so an
+// interface call with several possible callees counts as one site.
+func Aggregate(result *CallGraphResult, key func(pkgPath string) string) []AggregateEdge {
+	type pair struct{ from, to string }
+	type group struct {
+		sites   map[string]bool
+		callees map[string]bool
+	}
+	groups := make(map[pair]*group)
+
+	for _, edge := range result.CallEdges {
+		caller, callee := result.Functions[edge.Caller], result.Functions[edge.Callee]
+		p := pair{key(caller.Package), key(callee.Package)}
+		if p.from == p.to {
+			continue
+		}
+		g := groups[p]
+		if g == nil {
+			g = &group{sites: make(map[string]bool), callees: make(map[string]bool)}
+			groups[p] = g
+		}
+		// Instances of a generic function share its call sites
+		site := edge.Caller
+		if caller.Origin != "" {
+			site = caller.Origin
+		}
+		site = fmt.Sprintf("%s@%s:%d:%d", site, edge.File, edge.Line, edge.Column)
+		if edge.Line == 0 {
+			// Without a position every edge is its own site
+			site = edge.Caller + "->" + edge.Callee
+		}
+		g.sites[site] = true
+		g.callees[edge.Callee] = true
+	}
+
+	aggregated := make([]AggregateEdge, 0, len(groups))
+	for p, g := range groups {
+		edge := AggregateEdge{From: p.from, To: p.to, CallSites: len(g.sites)}
+		for callee := range g.callees {
+			edge.Callees = append(edge.Callees, callee)
+		}
+		sort.Strings(edge.Callees)
+		aggregated = append(aggregated, edge)
+	}
+	sort.Slice(aggregated, func(i, j int) bool {
+		if aggregated[i].From != aggregated[j].From {
+			return aggregated[i].From < aggregated[j].From
+		}
+		return aggregated[i].To < aggregated[j].To
+	})
+	return aggregated
+}
+
+// UnusedDependencies reports the external dependencies without reachable
+// functions, noting whether any of their packages is linked into prog.
+func UnusedDependencies(prog *ssa.Program, reachability []ModuleReachability) []UnusedDependency {
+	imported := make(map[string]bool)
+	for _, pkg := range prog.AllPackages() {
+		imported[pkg.Pkg.Path()] = true
+	}
+
+	unused := []UnusedDependency{}
+	for _, m := range reachability {
+		if m.Reachable {
+			continue
+		}
+		dep := UnusedDependency{
+			Label:      m.Label,
+			Name:       m.Name,
+			Version:    m.Version,
+			ImportPath: m.ImportPath,
+		}
+		for pkgPath := range imported {
+			if pkgPath == m.ImportPath || strings.HasPrefix(pkgPath, m.ImportPath+"/") {
+				dep.Imported = true
+				break
+			}
+		}
+		unused = append(unused, dep)
+	}
+	return unused
+}
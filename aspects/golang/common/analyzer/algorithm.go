@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// Algorithm selects how the call graph is constructed. The algorithms trade
+// precision for cost, from static (direct calls only) to rta+vta.
+type Algorithm string
+
+const (
+	// AlgorithmStatic records only statically dispatched calls.
+	AlgorithmStatic Algorithm = "static"
+	// AlgorithmCHA resolves dynamic calls by Class Hierarchy Analysis.
+	AlgorithmCHA Algorithm = "cha"
+	// AlgorithmRTA runs Rapid Type Analysis from the root package's main and
+	// init functions, so only reachable functions appear.
+	AlgorithmRTA Algorithm = "rta"
+	// AlgorithmVTA refines a CHA call graph with Variable Type Analysis.
+	AlgorithmVTA Algorithm = "vta"
+	// AlgorithmRTAVTA refines an RTA call graph with Variable Type Analysis,
+	// the most precise and most expensive combination.
+	AlgorithmRTAVTA Algorithm = "rta+vta"
+)
+
+// ParseAlgorithm validates an --algorithm flag value.
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch algorithm := Algorithm(s); algorithm {
+	case AlgorithmStatic, AlgorithmCHA, AlgorithmRTA, AlgorithmVTA, AlgorithmRTAVTA:
+		return algorithm, nil
+	}
+	return "", fmt.Errorf("unknown algorithm %q (want static, cha, rta, vta or rta+vta)", s)
+}
+
+// String returns the name recorded in CallGraphResult.Algorithm.
+func (a Algorithm) String() string {
+	return strings.ToUpper(string(a))
+}
+
+// needsRoots reports whether the algorithm starts from entrypoints.
+func (a Algorithm) needsRoots() bool {
+	return a == AlgorithmRTA || a == AlgorithmRTAVTA
+}
+
+// builderMode returns the SSA builder mode of the algorithm. RTA requires a
+// body per generic instantiation. The other algorithms only get them when
+// instances are requested, so that the calls made by each instance are
+// resolved with its type arguments; otherwise instances delegate to the
+// shared generic body.
+func (a Algorithm) builderMode(instances bool) ssa.BuilderMode {
+	if instances || a.needsRoots() {
+		return ssa.InstantiateGenerics
+	}
+	return 0
+}
+
+// BuildCallGraph builds the call graph of the program using the algorithm.
+// The roots seed RTA and are ignored by the other algorithms.
+func BuildCallGraph(prog *ssa.Program, algorithm Algorithm, roots []*ssa.Function) (*callgraph.Graph, error) {
+	if algorithm.needsRoots() && len(roots) == 0 {
+		return nil, fmt.Errorf("%s requires main or init functions in the root package", algorithm)
+	}
+
+	var cg *callgraph.Graph
+	switch algorithm {
+	case AlgorithmStatic:
+		cg = static.CallGraph(prog)
+	case AlgorithmCHA:
+		cg = cha.CallGraph(prog)
+	case AlgorithmRTA:
+		cg = rta.Analyze(roots, true).CallGraph
+	case AlgorithmVTA:
+		// Build CHA call graph first
+		chaCG := cha.CallGraph(prog)
+		deleteSyntheticNodes(chaCG)
+		cg = vta.CallGraph(ssautil.AllFunctions(prog), chaCG)
+	case AlgorithmRTAVTA:
+		// Restrict VTA to the functions RTA found reachable
+		res := rta.Analyze(roots, true)
+		reachable := make(map[*ssa.Function]bool, len(res.Reachable))
+		for fn := range res.Reachable {
+			reachable[fn] = true
+		}
+		deleteSyntheticNodes(res.CallGraph)
+		cg = vta.CallGraph(reachable, res.CallGraph)
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", algorithm)
+	}
+
+	deleteSyntheticNodes(cg)
+	return cg, nil
+}
+
+// entrypoints returns the main and init functions of the package.
+func entrypoints(pkg *ssa.Package) []*ssa.Function {
+	var roots []*ssa.Function
+	if pkg == nil {
+		return roots
+	}
+	for _, name := range []string{"main", "init"} {
+		if fn := pkg.Func(name); fn != nil {
+			roots = append(roots, fn)
+		}
+	}
+	return roots
+}
+
+// deleteSyntheticNodes inlines synthetic wrappers such as bound method
+// closures and interface method thunks into their callers, like
+// callgraph.Graph.DeleteSyntheticNodes. Functions without a body are kept:
+// they are the functions of packages loaded from export data, and removing
+// them would drop every call into those packages.
+func deleteSyntheticNodes(g *callgraph.Graph) {
+	// Hash all existing edges to avoid creating duplicates
+	edges := make(map[callgraph.Edge]bool)
+	for _, node := range g.Nodes {
+		for _, e := range node.Out {
+			edges[*e] = true
+		}
+	}
+	for fn, node := range g.Nodes {
+		if node == g.Root || fn == nil || fn.Syntax() != nil || len(fn.Blocks) == 0 || isPackageInit(fn) {
+			continue // keep
+		}
+		for _, in := range node.In {
+			for _, out := range node.Out {
+				edge := callgraph.Edge{Caller: in.Caller, Site: in.Site, Callee: out.Callee}
+				if edges[edge] {
+					continue
+				}
+				callgraph.AddEdge(in.Caller, in.Site, out.Callee)
+				edges[edge] = true
+			}
+		}
+		g.DeleteNode(node)
+	}
+}
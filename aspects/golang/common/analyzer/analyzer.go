@@ -0,0 +1,345 @@
+// Package analyzer builds call graphs for Go packages described by the
+// packages JSON the Bazel aspects write. It bundles the package loaders, SSA
+// construction, call graph extraction and result serialization shared by the
+// callgraph command.
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// Config controls a single analysis run.
+type Config struct {
+	// Loader selects the package loading strategy.
+	Loader LoaderMode
+	// Algorithm selects the call graph construction algorithm.
+	Algorithm Algorithm
+	// Instances builds a body per generic instantiation, so the calls of
+	// each instance are resolved and reported separately. RTA always does.
+	Instances bool
+	// WorkspaceRoot is the directory holding the go.mod of the workspace,
+	// required by LoaderWorkspace.
+	WorkspaceRoot string
+	// GoRoot is the Go SDK whose go command and standard library the
+	// loaders use; the go command on the PATH when empty. Loaders running
+	// the go command require the SDK's to be the first on the PATH. GOOS,
+	// GOARCH and Tags select the target platform and build tags of the
+	// analyzed target, the SDK's defaults when empty, and Cgo whether it
+	// is built with cgo, which selects the Go files of packages with cgo
+	// alternatives.
+	GoRoot string
+	GOOS   string
+	GOARCH string
+	Tags   []string
+	Cgo    bool
+	// RootOnly, Internal and Packages restrict the result to calls made
+	// from the root package, from any internal (workspace) package, or from
+	// packages matching one of the import path prefixes. When several are
+	// set, calls from any of the selected packages are kept; when none is,
+	// only calls from the root package are. AllPackages keeps the calls of
+	// every loaded package, dependencies and standard library included,
+	// which makes results several hundred times larger.
+	RootOnly    bool
+	Internal    bool
+	Packages    []string
+	AllPackages bool
+	// Tests loads the test variants and external test packages of the
+	// workspace loaders, treats Test, Benchmark and Fuzz functions as
+	// roots and labels every function as covered by tests or not. The
+	// export and exportdata loaders load no more than the packages JSON
+	// lists, so they warn that only its test packages are analyzed.
+	Tests bool
+	// Exposure reports, for every discovered route, the third-party
+	// modules and the sensitive standard library packages it reaches.
+	// SensitivePackages are the import path prefixes of those packages,
+	// DefaultExposurePackages when empty. FollowFormattingCalls follows
+	// the interface calls of Error and String methods, which the report
+	// skips by default.
+	Exposure              bool
+	SensitivePackages     []string
+	FollowFormattingCalls bool
+	// Taint reports the data flows from the results of source calls to the
+	// arguments of sink calls, DefaultTaintSources and DefaultTaintSinks
+	// when TaintSources or TaintSinks are empty.
+	Taint        bool
+	TaintSources []string
+	TaintSinks   []string
+	// Aggregate adds package-level and module-level graphs to the result.
+	Aggregate bool
+	// Dependencies enables reachability analysis against the external
+	// dependencies listed by merge_json_deps.
+	Dependencies []Dependency
+	// Advisories enables vulnerability reachability of the dependencies
+	// against an offline OSV database.
+	Advisories []OSVEntry
+	// Log receives progress messages; nil discards them.
+	Log io.Writer
+
+	// diagnostics are the problems met during the run
+	diagnostics []Diagnostic
+}
+
+func (c *Config) log() io.Writer {
+	if c.Log == nil {
+		return io.Discard
+	}
+	return c.Log
+}
+
+func (c *Config) logf(format string, args ...interface{}) {
+	fmt.Fprintf(c.log(), format, args...)
+}
+
+// Analyze loads the packages of the response, builds their call graph and
+// returns it as a result. The returned result is never nil: on error it is
+// the empty result for the root package. Its diagnostics list the error and
+// the problems met before it, so it can be told apart from a target without
+// calls, such as a root package without Go files, whose empty result has
+// none.
+func Analyze(cfg *Config, response *PackagesResponse) (result *CallGraphResult, err error) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgorithmVTA
+	}
+	cfg.diagnostics = nil
+
+	root := response.RootPackage()
+	result = NewResult(root)
+	result.Algorithm = cfg.Algorithm.String()
+	if root != nil {
+		cfg.logf("📦 Found target package: %s (ID: %s, Path: %s)\n", root.Name, root.ID, root.PkgPath)
+	}
+	defer func() {
+		// The caller reports the error itself, so it is not logged twice
+		if err != nil {
+			cfg.diagnostics = append(cfg.diagnostics, Diagnostic{Severity: SeverityError, Kind: DiagnosticAnalysisFailed, Package: result.PackageID, Message: err.Error()})
+		}
+		result.Diagnostics = cfg.diagnostics
+	}()
+	if withoutGoFiles(cfg, root) {
+		cfg.logf("📭 %s has no Go files to analyze\n", root.ID)
+		return result, nil
+	}
+
+	pkgs, err := Load(cfg, response)
+	if err != nil {
+		return result, fmt.Errorf("failed to load packages: %v", err)
+	}
+
+	scope := NewScope(cfg, result.ImportPath, pkgs, response)
+
+	// Filter valid packages for SSA
+	validPackages := filterValidPackages(pkgs)
+	reportPackages(cfg, pkgs, validPackages, scope.IsInternal)
+	if len(validPackages) == 0 {
+		return result, fmt.Errorf("no valid packages for SSA analysis")
+	}
+	cfg.logf("✅ Using %d valid packages for SSA\n", len(validPackages))
+
+	prog, initial, failed, err := BuildProgram(validPackages, cfg.Algorithm.builderMode(cfg.Instances))
+	reportBuildFailures(cfg, failed, scope.IsInternal)
+	if err != nil {
+		return result, err
+	}
+	defer cacheClosureLabels(prog)()
+	if len(prog.AllPackages()) == 0 {
+		return result, fmt.Errorf("no SSA packages built")
+	}
+
+	// Report the root package under its Go import path
+	rootPkg, matched := findRootPackage(initial, result.ImportPath)
+	switch {
+	case rootPkg == nil:
+		cfg.diagnose(SeverityWarning, DiagnosticFallback, result.PackageID, "no SSA package built for the root package %q, so only the discovered entrypoints are roots", result.ImportPath)
+	case !matched:
+		cfg.diagnose(SeverityWarning, DiagnosticFallback, result.PackageID, "no SSA package built for the root package %q, using %s instead", result.ImportPath, rootPkg.Pkg.Path())
+	}
+	if rootPkg != nil {
+		result.ImportPath = rootPkg.Pkg.Path()
+		scope.setRoot(result.ImportPath)
+	}
+
+	// Handlers and goroutines of the workspace are roots too, since the
+	// program never calls them directly
+	result.DiscoveredEntrypoints = DiscoverEntrypoints(prog, scope.IsInternal)
+	discovered := entrypointFunctions(result.DiscoveredEntrypoints)
+	for _, e := range result.DiscoveredEntrypoints {
+		if e.Route != "" {
+			cfg.logf("🚪 %s entrypoint %s -> %s\n", e.Kind, e.Label(), e.Function)
+		} else {
+			cfg.logf("🚪 %s entrypoint %s\n", e.Kind, e.Function)
+		}
+	}
+
+	var testRoots []*ssa.Function
+	if cfg.Tests {
+		testRoots = TestEntrypoints(initial)
+		cfg.logf("🧪 Found %d test entrypoints\n", len(testRoots))
+	}
+
+	roots := append(entrypoints(rootPkg), discovered...)
+	cg, err := BuildCallGraph(prog, cfg.Algorithm, append(roots, testRoots...))
+	if err != nil {
+		return result, err
+	}
+	cfg.logf("🕸️ %s call graph has %d nodes\n", result.Algorithm, len(cg.Nodes))
+
+	Extract(cg, result, scope)
+	AnnotateInstances(cg, result, NewInstantiationSites(validPackages))
+
+	if cfg.Tests {
+		roots := append(ReachabilityRoots(rootPkg), discovered...)
+		for _, fn := range testRoots {
+			result.TestEntrypoints = append(result.TestEntrypoints, FunctionID(fn))
+		}
+		result.Untested = TestCoverage(cg, roots, testRoots, result, scope)
+		cfg.logf("🧪 %d reachable workspace functions have no call path from a test\n", len(result.Untested))
+	}
+
+	modules := NewModuleResolver(validPackages, cfg.Dependencies)
+	if cfg.Aggregate {
+		result.PackageGraph = Aggregate(result, func(pkgPath string) string { return pkgPath })
+		if unresolved := modules.Unresolved(edgePackages(result)); len(unresolved) > 0 {
+			cfg.diagnose(SeverityWarning, DiagnosticFallback, result.PackageID, "no module known for %d packages, such as %s, so the module graph is omitted; pass --deps or load packages with module data", len(unresolved), unresolved[0])
+		} else {
+			result.ModuleGraph = Aggregate(result, func(pkgPath string) string {
+				module, _ := modules.Module(pkgPath)
+				return module
+			})
+		}
+		cfg.logf("🧩 Aggregated %d package edges and %d module edges\n", len(result.PackageGraph), len(result.ModuleGraph))
+	}
+
+	if cfg.Exposure {
+		sensitive := cfg.SensitivePackages
+		if len(sensitive) == 0 {
+			sensitive = DefaultExposurePackages
+		}
+		var unresolved []string
+		result.Exposure, unresolved = RouteExposures(cg, result.DiscoveredEntrypoints, modules, scope, sensitive, cfg.FollowFormattingCalls)
+		if len(unresolved) > 0 {
+			cfg.diagnose(SeverityWarning, DiagnosticFallback, result.PackageID, "no module known for %d packages the routes reach, such as %s, so the route exposures leave them out; pass --deps or load packages with module data", len(unresolved), unresolved[0])
+		}
+		cfg.logf("🔓 Computed dependency exposure of %d routes\n", len(result.Exposure))
+	}
+
+	if cfg.Taint {
+		sources, sinks := cfg.TaintSources, cfg.TaintSinks
+		if len(sources) == 0 {
+			sources = DefaultTaintSources
+		}
+		if len(sinks) == 0 {
+			sinks = DefaultTaintSinks
+		}
+		result.TaintFlows = TaintFlows(prog, cg, scope, sources, sinks)
+		cfg.logf("💧 Found %d taint flows from %d sources to %d sinks\n", len(result.TaintFlows), len(sources), len(sinks))
+	}
+
+	if cfg.Dependencies != nil {
+		roots := append(ReachabilityRoots(rootPkg), discovered...)
+		for _, fn := range roots {
+			result.Entrypoints = append(result.Entrypoints, FunctionID(fn))
+		}
+		result.Reachability = Reachability(cg, roots, cfg.Dependencies)
+		cfg.logf("🎯 Reachability computed from %d entrypoints for %d dependencies\n", len(roots), len(result.Reachability))
+		result.UnusedDependencies = UnusedDependencies(prog, result.Reachability)
+		cfg.logf("🧹 %d dependencies have no reachable calls\n", len(result.UnusedDependencies))
+
+		if cfg.Advisories != nil {
+			result.Vulnerabilities = VulnerabilityReachability(prog, cg, roots, cfg.Dependencies, cfg.Advisories)
+			cfg.logf("🛡️ Matched %d vulnerability findings against %d advisories\n", len(result.Vulnerabilities), len(cfg.Advisories))
+		}
+	}
+	cfg.logf("📊 Final result: %d functions, %d edges\n", result.TotalFuncs, result.TotalEdges)
+	return result, nil
+}
+
+// BuildProgram builds the SSA representation of the packages. It returns the
+// program and the SSA packages of the given packages, which are nil for
+// ill-typed packages. The SSA builder panics on syntax it does not support,
+// such as language features newer than golang.org/x/tools, so packages are
+// built one at a time and a package it fails on is rebuilt without function
+// bodies, like a dependency loaded from export data. The packages it failed
+// on are returned with the builder's error. It returns an error when the
+// builder fails on a package that has no function bodies left to leave out.
+func BuildProgram(pkgs []*packages.Package, mode ssa.BuilderMode) (*ssa.Program, []*ssa.Package, map[*packages.Package]error, error) {
+	byTypes := make(map[*types.Package]*packages.Package)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Types != nil {
+			byTypes[pkg.Types] = pkg
+		}
+	})
+
+	failed := make(map[*packages.Package]error)
+	for {
+		prog, initial := ssautil.AllPackages(pkgs, mode)
+		ssaPkg, err := buildPackages(prog)
+		if err == nil {
+			return prog, initial, failed, nil
+		}
+		pkg := byTypes[ssaPkg.Pkg]
+		if pkg == nil || len(pkg.Syntax) == 0 {
+			// Nothing left to leave out
+			return nil, nil, failed, fmt.Errorf("failed to build SSA package %s: %v", ssaPkg.Pkg.Path(), err)
+		}
+		failed[pkg] = err
+		pkg.Syntax, pkg.TypesInfo = nil, nil
+	}
+}
+
+// buildPackages builds the packages of the program in import path order,
+// returning the first one the SSA builder panics on.
+func buildPackages(prog *ssa.Program) (failed *ssa.Package, err error) {
+	all := prog.AllPackages()
+	sort.Slice(all, func(i, j int) bool { return all[i].Pkg.Path() < all[j].Pkg.Path() })
+	for _, pkg := range all {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					failed, err = pkg, fmt.Errorf("%v", r)
+				}
+			}()
+			pkg.Build()
+		}()
+		if err != nil {
+			return failed, err
+		}
+	}
+	return nil, nil
+}
+
+// withoutGoFiles reports whether the export data loader, which only loads
+// the files the packages JSON lists, has no Go files for the root package,
+// as for a go_library of assembly or embedded files only.
+func withoutGoFiles(cfg *Config, root *PackageJSON) bool {
+	return cfg.Loader == LoaderExportData && root != nil && len(root.GoFiles) == 0 && len(root.CompiledGoFiles) == 0
+}
+
+// findRootPackage returns the SSA package matching the root import path and
+// whether it matched. The workspace loader reports Bazel package paths such
+// as "src/main", so a package whose import path ends in the root path also
+// matches. Without a match the first initial package with functions is
+// used.
+func findRootPackage(initial []*ssa.Package, importPath string) (*ssa.Package, bool) {
+	var fallback *ssa.Package
+	for _, pkg := range initial {
+		if pkg == nil {
+			continue
+		}
+		path := pkg.Pkg.Path()
+		if importPath != "" && (path == importPath || strings.HasSuffix(path, "/"+importPath)) {
+			return pkg, true
+		}
+		if fallback == nil && len(pkg.Members) > 1 {
+			fallback = pkg
+		}
+	}
+	return fallback, false
+}
@@ -0,0 +1,143 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/example/go-aspects/aspects/golang/common/cgbin"
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+)
+
+// EncodeBinary writes the result in the compact binary encoding of the
+// cgbin package. The header, functions and edges are validated against the
+// schemas of their JSON counterparts first.
+func EncodeBinary(w io.Writer, result *CallGraphResult) error {
+	header, err := json.Marshal(resultHeader(result))
+	if err != nil {
+		return fmt.Errorf("failed to marshal result header: %v", err)
+	}
+	if err := schema.Validate(schema.ResultHeader, header); err != nil {
+		return err
+	}
+	g := &cgbin.Graph{Header: header}
+
+	ids := make([]string, 0, len(result.Functions))
+	for id := range result.Functions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		info := result.Functions[id]
+		if err := schema.ValidateValue(schema.ResultFunction, info); err != nil {
+			return err
+		}
+		index[id] = i
+		g.Functions = append(g.Functions, cgbin.Function{
+			ID:             info.ID,
+			Name:           info.Name,
+			Receiver:       info.Receiver,
+			Package:        info.Package,
+			Signature:      info.Signature,
+			Parameters:     info.Parameters,
+			Returns:        info.Returns,
+			TypeParams:     info.TypeParams,
+			Origin:         info.Origin,
+			TypeArgs:       info.TypeArgs,
+			InstantiatedAt: info.InstantiatedAt,
+			Coverage:       info.Coverage,
+		})
+	}
+	for _, edge := range result.CallEdges {
+		if err := schema.ValidateValue(schema.RecordEdge, edge); err != nil {
+			return err
+		}
+		caller, ok := index[edge.Caller]
+		if !ok {
+			return fmt.Errorf("edge references unknown caller %s", edge.Caller)
+		}
+		callee, ok := index[edge.Callee]
+		if !ok {
+			return fmt.Errorf("edge references unknown callee %s", edge.Callee)
+		}
+		g.Edges = append(g.Edges, cgbin.Edge{
+			Caller:       caller,
+			Callee:       callee,
+			File:         edge.File,
+			Line:         edge.Line,
+			Column:       edge.Column,
+			Kind:         edge.Kind,
+			Mode:         edge.Mode,
+			ReceiverType: edge.ReceiverType,
+			Scope:        edge.Scope,
+		})
+	}
+
+	return cgbin.Write(w, g)
+}
+
+// DecodeBinary reads a result written by EncodeBinary.
+func DecodeBinary(r io.Reader) (*CallGraphResult, error) {
+	g, err := cgbin.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var header CallGraphResult
+	if err := json.Unmarshal(g.Header, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse result header: %v", err)
+	}
+
+	functions := make(map[string]FunctionInfo, len(g.Functions))
+	for _, fn := range g.Functions {
+		functions[fn.ID] = FunctionInfo{
+			ID:             fn.ID,
+			Name:           fn.Name,
+			Receiver:       fn.Receiver,
+			Package:        fn.Package,
+			Signature:      fn.Signature,
+			Parameters:     fn.Parameters,
+			Returns:        fn.Returns,
+			TypeParams:     fn.TypeParams,
+			Origin:         fn.Origin,
+			TypeArgs:       fn.TypeArgs,
+			InstantiatedAt: fn.InstantiatedAt,
+			Coverage:       fn.Coverage,
+		}
+	}
+	edges := make([]NormalizedEdge, 0, len(g.Edges))
+	for _, edge := range g.Edges {
+		edges = append(edges, NormalizedEdge{
+			Caller:       g.Functions[edge.Caller].ID,
+			Callee:       g.Functions[edge.Callee].ID,
+			File:         edge.File,
+			Line:         edge.Line,
+			Column:       edge.Column,
+			Kind:         edge.Kind,
+			Mode:         edge.Mode,
+			ReceiverType: edge.ReceiverType,
+			Scope:        edge.Scope,
+		})
+	}
+	return assemble(&header, functions, edges)
+}
+
+func writeBinary(outputFile string, result *CallGraphResult) error {
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	if err := EncodeBinary(file, result); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return file.Close()
+}
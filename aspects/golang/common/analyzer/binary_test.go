@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	want := testResult(
+		"example.com/app.main -> example.com/app/store.Open",
+		"example.com/app/store.Open -> database/sql.Open",
+		"example.com/app.main -> fmt.Println",
+	)
+	want.Algorithm = AlgorithmCHA.String()
+	want.Entrypoints = []string{"example.com/app.main"}
+	want.Diagnostics = []Diagnostic{{Severity: SeverityWarning, Kind: DiagnosticFallback, Message: "no root package"}}
+	want.CallEdges[1].Kind, want.CallEdges[1].Mode = CallInterface, ModeDefer
+	want.CallEdges[1].ReceiverType = "example.com/app/store.DB"
+
+	var buf bytes.Buffer
+	if err := EncodeBinary(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Empty and missing reports encode alike, so compare the JSON
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("DecodeBinary(EncodeBinary(r)) = %s\nwant %s", gotJSON, wantJSON)
+	}
+}
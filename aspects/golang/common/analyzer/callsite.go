@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Call kinds recorded on call edges.
+const (
+	// CallStatic is a call whose callee is known at compile time.
+	CallStatic = "static"
+	// CallDynamic is a call through a function value or closure.
+	CallDynamic = "dynamic"
+	// CallInterface is a method call dispatched through an interface.
+	CallInterface = "interface"
+)
+
+// Call modes recorded on call edges.
+const (
+	ModeCall  = "call"
+	ModeGo    = "go"
+	ModeDefer = "defer"
+)
+
+// setCallSite fills the source position and call details of the edge from
+// its call instruction. Edges without a call instruction, such as those
+// from synthetic root nodes, are left without site details.
+func setCallSite(callEdge *CallEdge, edge *callgraph.Edge) {
+	if edge.Site == nil {
+		return
+	}
+	setSite(callEdge, edge.Caller.Func, edge.Site)
+}
+
+// setSite fills the source position and call details of the edge from the
+// call instruction of the caller.
+func setSite(callEdge *CallEdge, fn *ssa.Function, site ssa.CallInstruction) {
+
+	switch site.(type) {
+	case *ssa.Go:
+		callEdge.Mode = ModeGo
+	case *ssa.Defer:
+		callEdge.Mode = ModeDefer
+	default:
+		callEdge.Mode = ModeCall
+	}
+
+	common := site.Common()
+	switch {
+	case common.IsInvoke():
+		callEdge.Kind = CallInterface
+		callEdge.ReceiverType = common.Value.Type().String()
+	case common.StaticCallee() != nil:
+		callEdge.Kind = CallStatic
+	default:
+		callEdge.Kind = CallDynamic
+	}
+
+	if fn == nil || fn.Prog == nil || fn.Prog.Fset == nil || !site.Pos().IsValid() {
+		return
+	}
+	pos := fn.Prog.Fset.Position(site.Pos())
+	callEdge.File = pos.Filename
+	callEdge.Line = pos.Line
+	callEdge.Column = pos.Column
+}
@@ -0,0 +1,149 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// closureLabels holds, for each program being analyzed, the cache of the
+// labels of the closures of each parent function, which FunctionID needs
+// for every edge of a closure. Programs without a cache label closures
+// without caching.
+var closureLabels sync.Map // *ssa.Program -> *sync.Map of *ssa.Function -> map[*ssa.Function]string
+
+// cacheClosureLabels caches the closure labels of the program until the
+// returned function is called, which the analysis defers once it is done
+// with the program.
+func cacheClosureLabels(prog *ssa.Program) (release func()) {
+	closureLabels.Store(prog, new(sync.Map))
+	return func() { closureLabels.Delete(prog) }
+}
+
+// closureLabel returns the label identifying a closure within its parent,
+// independent of the other closures of the parent and of source positions:
+//
+//   - the name the function literal is bound to, by an assignment, a
+//     variable declaration or a keyed composite literal element;
+//   - otherwise a hash of the literal's source, formatted without comments
+//     or layout.
+//
+// Closures of the same parent sharing a label are told apart by a ~N
+// suffix, numbered in source order.
+func closureLabel(fn *ssa.Function) string {
+	parent := fn.Parent()
+	var labels map[*ssa.Function]string
+	if cache, ok := closureLabels.Load(fn.Prog); ok {
+		cached, ok := cache.(*sync.Map).Load(parent)
+		if !ok {
+			cached, _ = cache.(*sync.Map).LoadOrStore(parent, labelClosures(parent))
+		}
+		labels = cached.(map[*ssa.Function]string)
+	} else {
+		labels = labelClosures(parent)
+	}
+	if label, ok := labels[fn]; ok {
+		return label
+	}
+	// Closures missing from the parent's AnonFuncs keep their SSA number
+	return fn.Name()[strings.LastIndex(fn.Name(), "$")+1:]
+}
+
+// labelClosures labels the closures of the parent function.
+func labelClosures(parent *ssa.Function) map[*ssa.Function]string {
+	bindings := closureBindings(parent)
+	labels := make(map[*ssa.Function]string, len(parent.AnonFuncs))
+	seen := make(map[string]int)
+	for _, anon := range parent.AnonFuncs {
+		lit, ok := anon.Syntax().(*ast.FuncLit)
+		if !ok {
+			labels[anon] = anon.Name()[strings.LastIndex(anon.Name(), "$")+1:]
+			continue
+		}
+		label, ok := bindings[lit]
+		if !ok {
+			label = hashFuncLit(lit)
+		}
+		seen[label]++
+		if n := seen[label]; n > 1 {
+			label = fmt.Sprintf("%s~%d", label, n)
+		}
+		labels[anon] = label
+	}
+	return labels
+}
+
+// closureBindings returns the names the function literals directly in the
+// body of the parent are bound to. Package initializers have no syntax, so
+// their closures are left unnamed.
+func closureBindings(parent *ssa.Function) map[*ast.FuncLit]string {
+	var body *ast.BlockStmt
+	switch syntax := parent.Syntax().(type) {
+	case *ast.FuncDecl:
+		body = syntax.Body
+	case *ast.FuncLit:
+		body = syntax.Body
+	}
+	bindings := make(map[*ast.FuncLit]string)
+	if body == nil {
+		return bindings
+	}
+
+	bind := func(name ast.Expr, value ast.Expr) {
+		lit, ok := ast.Unparen(value).(*ast.FuncLit)
+		if !ok {
+			return
+		}
+		switch name := name.(type) {
+		case *ast.Ident:
+			if name.Name != "_" {
+				bindings[lit] = name.Name
+			}
+		case *ast.SelectorExpr:
+			bindings[lit] = name.Sel.Name
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Nested literals belong to the closure's own closures
+			return false
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				for i := range n.Rhs {
+					bind(n.Lhs[i], n.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == len(n.Values) {
+				for i := range n.Values {
+					bind(n.Names[i], n.Values[i])
+				}
+			}
+		case *ast.KeyValueExpr:
+			bind(n.Key, n.Value)
+		}
+		return true
+	})
+	return bindings
+}
+
+// hashFuncLit returns a short hash of the source of a function literal.
+// The literal is printed against an empty file set, so neither its position
+// nor its line breaks and comments change the hash.
+func hashFuncLit(lit *ast.FuncLit) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, token.NewFileSet(), lit); err != nil {
+		return "func"
+	}
+	h := fnv.New32a()
+	h.Write(buf.Bytes())
+	return fmt.Sprintf("%08x", h.Sum32())
+}
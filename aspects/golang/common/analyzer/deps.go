@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Dependency is a node of the merged dependency JSON written by
+// merge_json_deps from the endor_sca_info output group.
+type Dependency struct {
+	OriginalLabel string   `json:"original_label"`
+	Name          string   `json:"name"`
+	Version       string   `json:"version"`
+	Dependencies  []string `json:"dependencies"`
+	Internal      bool     `json:"internal"`
+	ImportPath    string   `json:"import_path"`
+}
+
+// Path returns the Go import path of the dependency, falling back to the
+// name derived from the repository when the target has no importpath.
+func (d Dependency) Path() string {
+	if d.ImportPath != "" {
+		return d.ImportPath
+	}
+	return d.Name
+}
+
+// ReadDependencies reads the {"nodes": [...]} file written by
+// merge_json_deps.
+func ReadDependencies(path string) ([]Dependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dependencies file: %v", err)
+	}
+
+	var deps struct {
+		Nodes []Dependency `json:"nodes"`
+	}
+	if err := json.Unmarshal(data, &deps); err != nil {
+		return nil, fmt.Errorf("failed to parse dependencies file %s: %v", path, err)
+	}
+	return deps.Nodes, nil
+}
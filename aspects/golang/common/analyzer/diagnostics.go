@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Severities of diagnostics. Errors mean the result is missing calls: the
+// analysis failed, or a workspace package could not be loaded or
+// type-checked. Warnings concern dependencies and the fallbacks taken,
+// which may make the result less complete or less precise.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Kinds of diagnostics.
+const (
+	// DiagnosticLoadError is a package that failed to be listed, parsed or
+	// loaded.
+	DiagnosticLoadError = "load_error"
+	// DiagnosticTypeError is a type error in a package.
+	DiagnosticTypeError = "type_error"
+	// DiagnosticSkippedPackage is a package left out of the call graph,
+	// because it could not be loaded or is ill-typed.
+	DiagnosticSkippedPackage = "skipped_package"
+	// DiagnosticFallback is a fallback taken by a loader or by the choice
+	// of the root package.
+	DiagnosticFallback = "fallback"
+	// DiagnosticAnalysisFailed is the error that stopped the analysis; the
+	// result holds no call graph.
+	DiagnosticAnalysisFailed = "analysis_failed"
+)
+
+// maxPackageErrors bounds the load and type errors reported per package.
+const maxPackageErrors = 10
+
+// Diagnostic is a problem met while analyzing a target. Results list them,
+// so an empty call graph can be told apart from a failed analysis.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	// Package is the ID of the package concerned, if any
+	Package string `json:"package,omitempty"`
+	// Position is the file:line:column of the error, if known
+	Position string `json:"position,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	b.WriteString(d.Severity + ": " + d.Kind)
+	if d.Package != "" {
+		b.WriteString(" in " + d.Package)
+	}
+	b.WriteString(": ")
+	if d.Position != "" {
+		b.WriteString(d.Position + ": ")
+	}
+	b.WriteString(d.Message)
+	return b.String()
+}
+
+// Errors returns the diagnostics with error severity.
+func Errors(diagnostics []Diagnostic) []Diagnostic {
+	var errs []Diagnostic
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	return errs
+}
+
+// report records a diagnostic of the run and logs it.
+func (c *Config) report(d Diagnostic) {
+	c.diagnostics = append(c.diagnostics, d)
+	icon := "⚠️"
+	switch d.Severity {
+	case SeverityError:
+		icon = "❌"
+	case SeverityInfo:
+		icon = "ℹ️"
+	}
+	c.logf("%s %s\n", icon, d)
+}
+
+func (c *Config) diagnose(severity, kind, pkg, format string, args ...interface{}) {
+	c.report(Diagnostic{Severity: severity, Kind: kind, Package: pkg, Message: fmt.Sprintf(format, args...)})
+}
+
+// reportPackages records the load and type errors of the loaded packages
+// and their dependencies, and the loaded packages left out of the call
+// graph: those without types, unless another instance of the package was
+// loaded, and the ill-typed ones, for which no SSA package is built.
+// Problems of workspace packages are errors, those of dependencies
+// warnings.
+func reportPackages(cfg *Config, pkgs, valid []*packages.Package, internal func(pkgPath string) bool) {
+	severity := func(pkg *packages.Package) string {
+		if internal(pkg.PkgPath) {
+			return SeverityError
+		}
+		return SeverityWarning
+	}
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for i, err := range pkg.Errors {
+			kind := DiagnosticLoadError
+			if err.Kind == packages.TypeError {
+				kind = DiagnosticTypeError
+			}
+			if i == maxPackageErrors {
+				cfg.diagnose(SeverityInfo, kind, pkg.ID, "%d more errors", len(pkg.Errors)-i)
+				break
+			}
+			position := err.Pos
+			if position == "-" {
+				position = ""
+			}
+			cfg.report(Diagnostic{Severity: severity(pkg), Kind: kind, Package: pkg.ID, Position: position, Message: err.Msg})
+		}
+	})
+
+	typed := make(map[string]bool)
+	packages.Visit(valid, nil, func(pkg *packages.Package) {
+		if pkg.Types != nil && !pkg.IllTyped {
+			typed[pkg.PkgPath] = true
+		}
+	})
+	skipped := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if typed[pkg.PkgPath] || skipped[pkg.PkgPath] {
+			continue
+		}
+		skipped[pkg.PkgPath] = true
+		if pkg.IllTyped && pkg.Types != nil {
+			cfg.diagnose(severity(pkg), DiagnosticSkippedPackage, pkg.ID, "%s has type errors, so its functions are missing from the call graph", pkg.PkgPath)
+		} else {
+			cfg.diagnose(severity(pkg), DiagnosticSkippedPackage, pkg.ID, "%s could not be loaded, so its functions are missing from the call graph", pkg.PkgPath)
+		}
+	}
+}
+
+// reportBuildFailures records the packages the SSA builder failed on, whose
+// functions have no bodies in the call graph. Failures of workspace
+// packages are errors, those of dependencies warnings.
+func reportBuildFailures(cfg *Config, failed map[*packages.Package]error, internal func(pkgPath string) bool) {
+	var pkgs []*packages.Package
+	for pkg := range failed {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].ID < pkgs[j].ID })
+	for _, pkg := range pkgs {
+		severity := SeverityWarning
+		if internal(pkg.PkgPath) {
+			severity = SeverityError
+		}
+		cfg.diagnose(severity, DiagnosticSkippedPackage, pkg.ID, "SSA construction of %s failed (%v), so the calls made by its functions are missing from the call graph", pkg.PkgPath, failed[pkg])
+	}
+}
@@ -0,0 +1,277 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+)
+
+// DefaultSensitivePackages are the packages a new call path into is
+// flagged by default when diffing results.
+var DefaultSensitivePackages = []string{"database/sql", "os/exec"}
+
+// EdgeKey identifies a call edge by its caller and callee function IDs,
+// regardless of how many call sites it has.
+type EdgeKey struct {
+	Caller string `json:"caller"`
+	Callee string `json:"callee"`
+}
+
+// SensitivePath is a call into a sensitive package from a function outside
+// it that the head result reaches from its entrypoints and the base result
+// does not: either the caller or the call is new.
+type SensitivePath struct {
+	Package  string `json:"package"`
+	Function string `json:"function"`
+	// Caller is the function calling into the sensitive package.
+	Caller string `json:"caller"`
+	// CallChain is a shortest call path from an entrypoint of the head
+	// result through the caller to the function.
+	CallChain []string `json:"call_chain"`
+}
+
+// ResultDiff is the difference between a base and a head CallGraphResult.
+type ResultDiff struct {
+	// SchemaVersion is the version of the callgraph_diff schema the diff
+	// follows
+	SchemaVersion    int       `json:"schema_version"`
+	BaseImportPath   string    `json:"base_import_path"`
+	HeadImportPath   string    `json:"head_import_path"`
+	AddedFunctions   []string  `json:"added_functions"`
+	RemovedFunctions []string  `json:"removed_functions"`
+	AddedEdges       []EdgeKey `json:"added_edges"`
+	RemovedEdges     []EdgeKey `json:"removed_edges"`
+	// External packages, dependencies and the standard library, that are
+	// reachable from the entrypoints in one result and not the other.
+	NewlyReachablePackages    []string        `json:"newly_reachable_packages"`
+	NoLongerReachablePackages []string        `json:"no_longer_reachable_packages"`
+	SensitivePaths            []SensitivePath `json:"sensitive_paths"`
+}
+
+// DiffResults compares two results of the same target, typically built from
+// two commits. Functions and edges are compared by function ID. Reachability
+// starts from the roots of the analysis: each result's entrypoints, its
+// discovered entrypoints and the main and init functions of its root
+// package. It follows the calls the results keep, so it only reaches into
+// dependencies for results built with --internal, --packages or
+// --all-packages. Sensitive packages match by import path prefix.
+func DiffResults(base, head *CallGraphResult, sensitive []string) *ResultDiff {
+	diff := &ResultDiff{
+		SchemaVersion:             schema.Version,
+		BaseImportPath:            base.ImportPath,
+		HeadImportPath:            head.ImportPath,
+		AddedFunctions:            []string{},
+		RemovedFunctions:          []string{},
+		AddedEdges:                []EdgeKey{},
+		RemovedEdges:              []EdgeKey{},
+		NewlyReachablePackages:    []string{},
+		NoLongerReachablePackages: []string{},
+		SensitivePaths:            []SensitivePath{},
+	}
+
+	for id := range head.Functions {
+		if _, ok := base.Functions[id]; !ok {
+			diff.AddedFunctions = append(diff.AddedFunctions, id)
+		}
+	}
+	for id := range base.Functions {
+		if _, ok := head.Functions[id]; !ok {
+			diff.RemovedFunctions = append(diff.RemovedFunctions, id)
+		}
+	}
+	sort.Strings(diff.AddedFunctions)
+	sort.Strings(diff.RemovedFunctions)
+
+	baseEdges, headEdges := resultEdges(base), resultEdges(head)
+	for edge := range headEdges {
+		if !baseEdges[edge] {
+			diff.AddedEdges = append(diff.AddedEdges, edge)
+		}
+	}
+	for edge := range baseEdges {
+		if !headEdges[edge] {
+			diff.RemovedEdges = append(diff.RemovedEdges, edge)
+		}
+	}
+	sortEdgeKeys(diff.AddedEdges)
+	sortEdgeKeys(diff.RemovedEdges)
+
+	// A package internal to either result is part of the workspace
+	internal := internalPackages(base)
+	for pkg := range internalPackages(head) {
+		internal[pkg] = true
+	}
+	baseWalk, headWalk := walkResult(base, baseEdges), walkResult(head, headEdges)
+	basePkgs, headPkgs := baseWalk.packages(base, internal), headWalk.packages(head, internal)
+	for pkg := range headPkgs {
+		if !basePkgs[pkg] {
+			diff.NewlyReachablePackages = append(diff.NewlyReachablePackages, pkg)
+		}
+	}
+	for pkg := range basePkgs {
+		if !headPkgs[pkg] {
+			diff.NoLongerReachablePackages = append(diff.NoLongerReachablePackages, pkg)
+		}
+	}
+	sort.Strings(diff.NewlyReachablePackages)
+	sort.Strings(diff.NoLongerReachablePackages)
+
+	// Report the reachable calls into sensitive packages that the base
+	// result does not reach, so a new caller of an already reachable
+	// sensitive function is flagged too. Callers come in walk order, so the
+	// shortest paths come first.
+	for _, caller := range headWalk.order {
+		if matchesPackage(head.Functions[caller].Package, sensitive) {
+			continue
+		}
+		_, reachedBefore := baseWalk.parent[caller]
+		for _, callee := range headWalk.callees[caller] {
+			pkg := head.Functions[callee].Package
+			if !matchesPackage(pkg, sensitive) {
+				continue
+			}
+			if reachedBefore && baseEdges[EdgeKey{caller, callee}] {
+				continue
+			}
+			diff.SensitivePaths = append(diff.SensitivePaths, SensitivePath{
+				Package:   pkg,
+				Function:  callee,
+				Caller:    caller,
+				CallChain: append(headWalk.path(caller), callee),
+			})
+		}
+	}
+	return diff
+}
+
+// resultEdges returns the distinct caller to callee pairs of a result.
+func resultEdges(result *CallGraphResult) map[EdgeKey]bool {
+	edges := make(map[EdgeKey]bool)
+	for caller, callees := range result.CallGraph {
+		for _, callee := range callees {
+			edges[EdgeKey{caller, callee}] = true
+		}
+	}
+	for _, edge := range result.CallEdges {
+		edges[EdgeKey{edge.Caller, edge.Callee}] = true
+	}
+	return edges
+}
+
+func sortEdgeKeys(edges []EdgeKey) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Caller != edges[j].Caller {
+			return edges[i].Caller < edges[j].Caller
+		}
+		return edges[i].Callee < edges[j].Callee
+	})
+}
+
+// internalPackages returns the workspace packages of a result: its root
+// package and the packages on both ends of its internal edges.
+func internalPackages(result *CallGraphResult) map[string]bool {
+	internal := make(map[string]bool)
+	if result.ImportPath != "" {
+		internal[result.ImportPath] = true
+	}
+	for _, edge := range result.CallEdges {
+		if edge.Scope == ScopeInternal {
+			for _, id := range []string{edge.Caller, edge.Callee} {
+				if pkg := result.Functions[id].Package; pkg != "" {
+					internal[pkg] = true
+				}
+			}
+		}
+	}
+	return internal
+}
+
+// resultRoots returns the IDs of the functions reachability starts from,
+// the same roots Analyze builds the call graph from: the entrypoints of
+// the result, its discovered entrypoints, and the main and init functions
+// of its root package.
+func resultRoots(result *CallGraphResult) []string {
+	roots := append([]string(nil), result.Entrypoints...)
+	for _, e := range result.DiscoveredEntrypoints {
+		roots = append(roots, e.Function)
+	}
+	for _, name := range []string{"main", "init"} {
+		id := result.ImportPath + "." + name
+		if _, ok := result.Functions[id]; ok {
+			roots = append(roots, id)
+		}
+	}
+	return roots
+}
+
+// resultWalk is a breadth-first traversal of a result's call graph by
+// function ID, mirroring callGraphWalk.
+type resultWalk struct {
+	order   []string
+	parent  map[string]string
+	callees map[string][]string
+}
+
+func walkResult(result *CallGraphResult, edges map[EdgeKey]bool) *resultWalk {
+	walk := &resultWalk{parent: make(map[string]string), callees: make(map[string][]string)}
+	for edge := range edges {
+		walk.callees[edge.Caller] = append(walk.callees[edge.Caller], edge.Callee)
+	}
+	for _, callees := range walk.callees {
+		sort.Strings(callees)
+	}
+
+	for _, root := range resultRoots(result) {
+		if _, ok := walk.parent[root]; ok {
+			continue
+		}
+		walk.parent[root] = ""
+		walk.order = append(walk.order, root)
+	}
+	for i := 0; i < len(walk.order); i++ {
+		caller := walk.order[i]
+		for _, callee := range walk.callees[caller] {
+			if _, ok := walk.parent[callee]; ok {
+				continue
+			}
+			walk.parent[callee] = caller
+			walk.order = append(walk.order, callee)
+		}
+	}
+	return walk
+}
+
+// path returns the call chain from a root to the reached function.
+func (w *resultWalk) path(id string) []string {
+	var path []string
+	for cur := id; cur != ""; cur = w.parent[cur] {
+		path = append(path, cur)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// packages returns the reachable packages outside the workspace.
+func (w *resultWalk) packages(result *CallGraphResult, internal map[string]bool) map[string]bool {
+	pkgs := make(map[string]bool)
+	for _, id := range w.order {
+		pkg := result.Functions[id].Package
+		if pkg != "" && !internal[pkg] {
+			pkgs[pkg] = true
+		}
+	}
+	return pkgs
+}
+
+// matchesPackage reports whether pkg is one of the prefixes or below one.
+func matchesPackage(pkg string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
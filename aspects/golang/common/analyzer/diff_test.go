@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+)
+
+func TestDiffResults(t *testing.T) {
+	base := testResult(
+		"example.com/app.main -> example.com/app/store.Open",
+		"example.com/app/store.Open -> database/sql.Open",
+		"example.com/app.main -> fmt.Println",
+		"example.com/app.unused -> os/exec.Command",
+	)
+	head := testResult(
+		"example.com/app.main -> example.com/app/store.Open",
+		"example.com/app/store.Open -> database/sql.Open",
+		"example.com/app.main -> example.com/app/store.Migrate",
+		"example.com/app/store.Migrate -> database/sql.Open",
+		"example.com/app/store.Migrate -> strings.ToLower",
+		"example.com/app.unused -> os/exec.Command",
+	)
+
+	diff := DiffResults(base, head, DefaultSensitivePackages)
+	if err := schema.ValidateValue(schema.CallGraphDiff, diff); err != nil {
+		t.Fatal(err)
+	}
+
+	wantAdded := []string{"example.com/app/store.Migrate", "strings.ToLower"}
+	if !reflect.DeepEqual(diff.AddedFunctions, wantAdded) {
+		t.Errorf("added functions = %q, want %q", diff.AddedFunctions, wantAdded)
+	}
+	if want := []string{"fmt.Println"}; !reflect.DeepEqual(diff.RemovedFunctions, want) {
+		t.Errorf("removed functions = %q, want %q", diff.RemovedFunctions, want)
+	}
+	wantEdges := []EdgeKey{
+		{"example.com/app.main", "example.com/app/store.Migrate"},
+		{"example.com/app/store.Migrate", "database/sql.Open"},
+		{"example.com/app/store.Migrate", "strings.ToLower"},
+	}
+	if !reflect.DeepEqual(diff.AddedEdges, wantEdges) {
+		t.Errorf("added edges = %v, want %v", diff.AddedEdges, wantEdges)
+	}
+	if want := []EdgeKey{{"example.com/app.main", "fmt.Println"}}; !reflect.DeepEqual(diff.RemovedEdges, want) {
+		t.Errorf("removed edges = %v, want %v", diff.RemovedEdges, want)
+	}
+
+	// Internal packages such as example.com/app/store are not reported,
+	// nor are packages only unreachable functions call
+	if want := []string{"strings"}; !reflect.DeepEqual(diff.NewlyReachablePackages, want) {
+		t.Errorf("newly reachable packages = %q, want %q", diff.NewlyReachablePackages, want)
+	}
+	if want := []string{"fmt"}; !reflect.DeepEqual(diff.NoLongerReachablePackages, want) {
+		t.Errorf("no longer reachable packages = %q, want %q", diff.NoLongerReachablePackages, want)
+	}
+
+	// database/sql.Open was already reachable, but Migrate is a new caller
+	want := []SensitivePath{{
+		Package:   "database/sql",
+		Function:  "database/sql.Open",
+		Caller:    "example.com/app/store.Migrate",
+		CallChain: []string{"example.com/app.main", "example.com/app/store.Migrate", "database/sql.Open"},
+	}}
+	if !reflect.DeepEqual(diff.SensitivePaths, want) {
+		t.Errorf("sensitive paths = %+v, want %+v", diff.SensitivePaths, want)
+	}
+}
+
+func TestDiffResultsUnchanged(t *testing.T) {
+	base := testResult(
+		"example.com/app.main -> example.com/app/store.Open",
+		"example.com/app/store.Open -> database/sql.Open",
+		"database/sql.Open -> database/sql.driverConn",
+	)
+	diff := DiffResults(base, base, DefaultSensitivePackages)
+	empty := &ResultDiff{
+		SchemaVersion:             schema.Version,
+		BaseImportPath:            testRoot,
+		HeadImportPath:            testRoot,
+		AddedFunctions:            []string{},
+		RemovedFunctions:          []string{},
+		AddedEdges:                []EdgeKey{},
+		RemovedEdges:              []EdgeKey{},
+		NewlyReachablePackages:    []string{},
+		NoLongerReachablePackages: []string{},
+		SensitivePaths:            []SensitivePath{},
+	}
+	if !reflect.DeepEqual(diff, empty) {
+		t.Errorf("diff of a result with itself = %+v, want %+v", diff, empty)
+	}
+}
+
+func TestDiffResultsDiscoveredEntrypoints(t *testing.T) {
+	base := testResult(
+		"example.com/app.main -> example.com/app.serve",
+	)
+	head := testResult(
+		"example.com/app.main -> example.com/app.serve",
+		"example.com/app.handleRun -> os/exec.Command",
+		"os/exec.Command -> os/exec.lookExtensions",
+	)
+	// The handler is only registered, so reachability starts from it too
+	head.DiscoveredEntrypoints = []Entrypoint{{Kind: EntrypointHTTP, Route: "/run", Function: "example.com/app.handleRun"}}
+
+	diff := DiffResults(base, head, DefaultSensitivePackages)
+	if want := []string{"os/exec"}; !reflect.DeepEqual(diff.NewlyReachablePackages, want) {
+		t.Errorf("newly reachable packages = %q, want %q", diff.NewlyReachablePackages, want)
+	}
+	// Calls within the sensitive package are not reported again
+	want := []SensitivePath{{
+		Package:   "os/exec",
+		Function:  "os/exec.Command",
+		Caller:    "example.com/app.handleRun",
+		CallChain: []string{"example.com/app.handleRun", "os/exec.Command"},
+	}}
+	if !reflect.DeepEqual(diff.SensitivePaths, want) {
+		t.Errorf("sensitive paths = %+v, want %+v", diff.SensitivePaths, want)
+	}
+}
@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// driverMode is the load mode requested from the driver: the metadata,
+// files, imports and export data of the patterns and their dependencies.
+const driverMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedImports | packages.NeedDeps | packages.NeedExportFile |
+	packages.NeedModule
+
+// DriverConfig configures a gopackagesdriver query.
+type DriverConfig struct {
+	// Driver is the path of the gopackagesdriver binary, such as the one
+	// built from @rules_go//go/tools/gopackagesdriver.
+	Driver string
+	// Dir is the working directory of the driver, normally the workspace.
+	Dir string
+	// Env is the environment of the driver and of the build it runs; nil
+	// means the current environment.
+	Env []string
+	// BuildFlags are passed to the underlying build system.
+	BuildFlags []string
+	// Tests requests the test variants of the packages too.
+	Tests bool
+	// Log receives the driver's stderr; nil discards it.
+	Log io.Writer
+}
+
+// QueryDriver runs the driver with the GOPACKAGESDRIVER protocol: the
+// DriverRequest is written to its stdin, the patterns are its arguments and
+// the DriverResponse is read from its stdout.
+func QueryDriver(cfg *DriverConfig, patterns ...string) (*PackagesResponse, error) {
+	env := cfg.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	request, err := json.Marshal(&packages.DriverRequest{
+		Mode:       driverMode,
+		Env:        env,
+		BuildFlags: cfg.BuildFlags,
+		Tests:      cfg.Tests,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode driver request: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(cfg.Driver, patterns...)
+	cmd.Dir = cfg.Dir
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = io.Discard
+	if cfg.Log != nil {
+		cmd.Stderr = cfg.Log
+	}
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gopackagesdriver %s failed: %v", strings.Join(patterns, " "), err)
+	}
+
+	response, err := ReadPackages(&stdout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gopackagesdriver response: %v", err)
+	}
+	if response.NotHandled {
+		return nil, fmt.Errorf("gopackagesdriver did not handle %s", strings.Join(patterns, " "))
+	}
+	return response, nil
+}
@@ -0,0 +1,402 @@
+package analyzer
+
+import (
+	"go/constant"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// Kinds of discovered entrypoints.
+const (
+	// EntrypointHTTP is an HTTP handler registered with a router.
+	EntrypointHTTP = "http"
+	// EntrypointGRPC is a method of a registered gRPC service.
+	EntrypointGRPC = "grpc"
+	// EntrypointGoroutine is a function started by a go statement.
+	EntrypointGoroutine = "goroutine"
+)
+
+// Import paths of the routing packages entrypoints are discovered for.
+const (
+	netHTTPPath    = "net/http"
+	gorillaMuxPath = "github.com/gorilla/mux"
+	ginPath        = "github.com/gin-gonic/gin"
+)
+
+// ginMethods are the RouterGroup methods registering a route for one HTTP
+// method, which is their name.
+var ginMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "HEAD": true, "OPTIONS": true,
+}
+
+// grpcRegister matches the registration functions protoc-gen-go-grpc
+// generates for a service, such as RegisterGreeterServer.
+var grpcRegister = regexp.MustCompile(`^Register(\w+)Server$`)
+
+// Entrypoint is a function the program hands to a framework or to the
+// scheduler rather than calling it itself: an HTTP handler, a gRPC method
+// or the body of a goroutine.
+type Entrypoint struct {
+	Kind string `json:"kind"`
+	// Framework is the package the entrypoint is registered with, e.g.
+	// net/http, gorilla/mux, gin or grpc.
+	Framework string `json:"framework,omitempty"`
+	// Methods are the HTTP methods of the route, empty for any method.
+	Methods []string `json:"methods,omitempty"`
+	// Route is the HTTP path pattern including group prefixes, or the
+	// Service/Method name of a gRPC method.
+	Route    string `json:"route,omitempty"`
+	Function string `json:"function"`
+	// Position of the registration or go statement
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+
+	fn *ssa.Function
+}
+
+// Label returns the route of the entrypoint with its methods, such as
+// "GET /hash/{data}", or its function when it has no route.
+func (e Entrypoint) Label() string {
+	switch {
+	case e.Route != "" && len(e.Methods) > 0:
+		return strings.Join(e.Methods, ",") + " " + e.Route
+	case e.Route != "":
+		return e.Route
+	}
+	return e.Function
+}
+
+// DiscoverEntrypoints finds the entrypoints registered by the functions of
+// the packages selected by keep: handlers passed to http.HandleFunc and
+// http.Handle, mux.Router.HandleFunc and Handle, gin routes including
+// groups, gRPC service registrations and go statements. Handlers that are
+// not statically known, such as handlers returned by a call, are skipped.
+func DiscoverEntrypoints(prog *ssa.Program, keep func(pkgPath string) bool) []Entrypoint {
+	var found []Entrypoint
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Pkg == nil || !keep(fn.Pkg.Pkg.Path()) {
+			continue
+		}
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.Go:
+					if callee := instr.Call.StaticCallee(); callee != nil {
+						found = append(found, newEntrypoint(instr, EntrypointGoroutine, "", callee))
+					}
+				case *ssa.Call:
+					found = append(found, registeredEntrypoints(prog, instr)...)
+				}
+			}
+		}
+	}
+
+	for i := range found {
+		found[i].Function = FunctionID(found[i].fn)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Function != b.Function {
+			return a.Function < b.Function
+		}
+		return a.Label() < b.Label()
+	})
+	return found
+}
+
+// entrypointFunctions returns the functions of the entrypoints, without
+// duplicates.
+func entrypointFunctions(entrypoints []Entrypoint) []*ssa.Function {
+	seen := make(map[*ssa.Function]bool)
+	var fns []*ssa.Function
+	for _, e := range entrypoints {
+		if !seen[e.fn] {
+			seen[e.fn] = true
+			fns = append(fns, e.fn)
+		}
+	}
+	return fns
+}
+
+func newEntrypoint(instr ssa.Instruction, kind, framework string, fn *ssa.Function) Entrypoint {
+	e := Entrypoint{Kind: kind, Framework: framework, fn: fn}
+	if pos := instr.Pos(); pos.IsValid() {
+		position := instr.Parent().Prog.Fset.Position(pos)
+		e.File, e.Line = position.Filename, position.Line
+	}
+	return e
+}
+
+// registeredEntrypoints returns the entrypoints a call registers, if it
+// calls one of the known registration functions.
+func registeredEntrypoints(prog *ssa.Program, call *ssa.Call) []Entrypoint {
+	callee := call.Call.StaticCallee()
+	if callee == nil {
+		return nil
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok || obj.Pkg() == nil {
+		return nil
+	}
+	pkg, recv, name := obj.Pkg().Path(), receiverName(obj), obj.Name()
+	args := call.Call.Args
+	if len(args) == 0 {
+		return nil
+	}
+	router := args[0]
+
+	switch {
+	case pkg == netHTTPPath && (recv == "" || recv == "ServeMux") && (name == "HandleFunc" || name == "Handle"):
+		// http.HandleFunc(pattern, handler) or mux.HandleFunc(pattern, handler);
+		// Go 1.22 patterns may start with a method
+		if recv != "" {
+			args = args[1:]
+		}
+		e, ok := httpEntrypoint(prog, call, "net/http", args[0], args[1])
+		if !ok {
+			return nil
+		}
+		if method, path, ok := strings.Cut(e.Route, " "); ok {
+			e.Methods, e.Route = []string{method}, strings.TrimSpace(path)
+		}
+		return []Entrypoint{e}
+
+	case pkg == gorillaMuxPath && recv == "Router" && (name == "HandleFunc" || name == "Handle"):
+		e, ok := httpEntrypoint(prog, call, "gorilla/mux", args[1], args[2])
+		if !ok {
+			return nil
+		}
+		e.Route = routePrefix(router) + e.Route
+		e.Methods = muxMethods(call)
+		return []Entrypoint{e}
+
+	case pkg == ginPath && recv == "RouterGroup" && (ginMethods[name] || name == "Any" || name == "Handle"):
+		// The last handler serves the route, the others are middleware
+		var method string
+		if name == "Handle" {
+			method, _ = constString(args[1])
+			args = args[1:]
+		} else if name != "Any" {
+			method = name
+		}
+		handlers := sliceElements(args[2])
+		if len(handlers) == 0 {
+			return nil
+		}
+		e, ok := httpEntrypoint(prog, call, "gin", args[1], handlers[len(handlers)-1])
+		if !ok {
+			return nil
+		}
+		e.Route = routePrefix(router) + e.Route
+		if method != "" {
+			e.Methods = []string{method}
+		}
+		return []Entrypoint{e}
+
+	case recv == "" && len(args) == 2 && grpcRegister.MatchString(name):
+		return grpcEntrypoints(prog, call, grpcRegister.FindStringSubmatch(name)[1], args[1])
+	}
+	return nil
+}
+
+// httpEntrypoint returns the entrypoint of a handler registered for a path.
+func httpEntrypoint(prog *ssa.Program, call *ssa.Call, framework string, path, handler ssa.Value) (Entrypoint, bool) {
+	fn := handlerFunction(prog, handler)
+	if fn == nil {
+		return Entrypoint{}, false
+	}
+	e := newEntrypoint(call, EntrypointHTTP, framework, fn)
+	e.Route, _ = constString(path)
+	return e, true
+}
+
+// grpcEntrypoints returns the methods of the service implementation passed
+// to a gRPC registration function.
+func grpcEntrypoints(prog *ssa.Program, call *ssa.Call, service string, srv ssa.Value) []Entrypoint {
+	iface, ok := srv.Type().Underlying().(*types.Interface)
+	mi, isMakeInterface := srv.(*ssa.MakeInterface)
+	if !ok || !isMakeInterface {
+		return nil
+	}
+	var found []Entrypoint
+	for i := 0; i < iface.NumMethods(); i++ {
+		method := iface.Method(i)
+		if !method.Exported() {
+			continue // mustEmbedUnimplemented...
+		}
+		if fn := prog.LookupMethod(mi.X.Type(), method.Pkg(), method.Name()); fn != nil {
+			e := newEntrypoint(call, EntrypointGRPC, "grpc", fn)
+			e.Route = service + "/" + method.Name()
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// handlerFunction resolves the function a handler value runs: a function,
+// a closure or method value, a conversion to a handler type such as
+// http.HandlerFunc, or the ServeHTTP method of an http.Handler.
+func handlerFunction(prog *ssa.Program, v ssa.Value) *ssa.Function {
+	switch v := v.(type) {
+	case *ssa.Function:
+		return wrappedFunction(v)
+	case *ssa.MakeClosure:
+		return handlerFunction(prog, v.Fn)
+	case *ssa.ChangeType:
+		return handlerFunction(prog, v.X)
+	case *ssa.Convert:
+		return handlerFunction(prog, v.X)
+	case *ssa.MakeInterface:
+		if fn := handlerFunction(prog, v.X); fn != nil {
+			return fn
+		}
+		if sel := prog.MethodSets.MethodSet(v.X.Type()).Lookup(nil, "ServeHTTP"); sel != nil {
+			if fn := prog.MethodValue(sel); fn != nil {
+				return declaredFunction(fn)
+			}
+		}
+	}
+	return nil
+}
+
+// wrappedFunction returns the method a synthetic bound method or thunk
+// wrapper calls, or fn itself for other functions.
+func wrappedFunction(fn *ssa.Function) *ssa.Function {
+	if fn.Synthetic == "" || len(fn.Blocks) == 0 {
+		return fn
+	}
+	for _, instr := range fn.Blocks[0].Instrs {
+		if call, ok := instr.(*ssa.Call); ok {
+			if callee := call.Call.StaticCallee(); callee != nil {
+				return callee
+			}
+		}
+	}
+	return fn
+}
+
+// routePrefix returns the path prefix of a router value built by gin's
+// RouterGroup.Group or mux's Router.PathPrefix(...).Subrouter(), or "" when
+// the router is not built from a known prefix in the same function.
+func routePrefix(router ssa.Value) string {
+	call, ok := router.(*ssa.Call)
+	if !ok {
+		return ""
+	}
+	callee := call.Call.StaticCallee()
+	if callee == nil {
+		return ""
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok || obj.Pkg() == nil {
+		return ""
+	}
+	args := call.Call.Args
+	switch pkg, recv, name := obj.Pkg().Path(), receiverName(obj), obj.Name(); {
+	case pkg == ginPath && recv == "RouterGroup" && name == "Group":
+		prefix, _ := constString(args[1])
+		return routePrefix(args[0]) + prefix
+	case pkg == gorillaMuxPath && recv == "Route" && name == "Subrouter":
+		return routePrefix(args[0])
+	case pkg == gorillaMuxPath && (recv == "Router" || recv == "Route") && name == "PathPrefix":
+		prefix, _ := constString(args[1])
+		return routePrefix(args[0]) + prefix
+	}
+	return ""
+}
+
+// muxMethods returns the methods a mux route is restricted to by a
+// Methods call on the route returned by the registration.
+func muxMethods(registration *ssa.Call) []string {
+	var methods []string
+	for _, ref := range *registration.Referrers() {
+		call, ok := ref.(*ssa.Call)
+		if !ok || len(call.Call.Args) != 2 || call.Call.Args[0] != registration {
+			continue
+		}
+		if callee := call.Call.StaticCallee(); callee == nil || callee.Name() != "Methods" {
+			continue
+		}
+		for _, v := range sliceElements(call.Call.Args[1]) {
+			if method, ok := constString(v); ok {
+				methods = append(methods, method)
+			}
+		}
+	}
+	return methods
+}
+
+// receiverName returns the name of the receiver type of a method, or "" for
+// a function.
+func receiverName(obj *types.Func) string {
+	recv := obj.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return ""
+	}
+	if named := namedReceiver(recv.Type()); named != nil {
+		return named.Obj().Name()
+	}
+	return ""
+}
+
+// constString returns the value of a string constant.
+func constString(v ssa.Value) (string, bool) {
+	c, ok := v.(*ssa.Const)
+	if !ok || c.Value == nil || c.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(c.Value), true
+}
+
+// sliceElements returns the values stored into the array backing a variadic
+// argument slice, in index order.
+func sliceElements(v ssa.Value) []ssa.Value {
+	slice, ok := v.(*ssa.Slice)
+	if !ok {
+		return nil
+	}
+	alloc, ok := slice.X.(*ssa.Alloc)
+	if !ok {
+		return nil
+	}
+	elements := make(map[int64]ssa.Value)
+	var n int64
+	for _, ref := range *alloc.Referrers() {
+		addr, ok := ref.(*ssa.IndexAddr)
+		if !ok {
+			continue
+		}
+		index, ok := addr.Index.(*ssa.Const)
+		if !ok {
+			continue
+		}
+		i := index.Int64()
+		for _, use := range *addr.Referrers() {
+			if store, ok := use.(*ssa.Store); ok && store.Addr == addr {
+				elements[i] = store.Val
+				if i+1 > n {
+					n = i + 1
+				}
+			}
+		}
+	}
+	values := make([]ssa.Value, 0, n)
+	for i := int64(0); i < n; i++ {
+		if v, ok := elements[i]; ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
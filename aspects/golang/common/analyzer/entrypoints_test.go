@@ -0,0 +1,179 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// entrypointModules are stand-ins for the routing modules, declaring the
+// part of their API entrypoints are discovered for.
+var entrypointModules = map[string]string{
+	"go.mod": `module example.com
+
+go 1.23
+
+require (
+	github.com/gin-gonic/gin v1.0.0
+	github.com/gorilla/mux v1.0.0
+)
+
+replace (
+	github.com/gin-gonic/gin => ./third_party/gin
+	github.com/gorilla/mux => ./third_party/mux
+)
+`,
+	"third_party/gin/go.mod": "module github.com/gin-gonic/gin\n\ngo 1.23\n",
+	"third_party/gin/gin.go": `package gin
+
+type Context struct{}
+
+type HandlerFunc func(*Context)
+
+type RouterGroup struct{}
+
+func (g *RouterGroup) Group(path string, handlers ...HandlerFunc) *RouterGroup { return g }
+func (g *RouterGroup) GET(path string, handlers ...HandlerFunc) {}
+func (g *RouterGroup) Any(path string, handlers ...HandlerFunc) {}
+func (g *RouterGroup) Handle(method, path string, handlers ...HandlerFunc) {}
+
+type Engine struct{ RouterGroup }
+
+func Default() *Engine { return &Engine{} }
+`,
+	"third_party/mux/go.mod": "module github.com/gorilla/mux\n\ngo 1.23\n",
+	"third_party/mux/mux.go": `package mux
+
+import "net/http"
+
+type Router struct{}
+
+type Route struct{}
+
+func NewRouter() *Router { return &Router{} }
+func (r *Router) PathPrefix(path string) *Route { return &Route{} }
+func (r *Router) HandleFunc(path string, f func(http.ResponseWriter, *http.Request)) *Route { return &Route{} }
+func (r *Route) Subrouter() *Router { return &Router{} }
+func (r *Route) Methods(methods ...string) *Route { return r }
+`,
+	"pb/pb.go": `package pb
+
+type GreeterServer interface {
+	SayHello()
+	mustEmbedUnimplementedGreeterServer()
+}
+
+type UnimplementedGreeterServer struct{}
+
+func (UnimplementedGreeterServer) mustEmbedUnimplementedGreeterServer() {}
+
+func RegisterGreeterServer(s any, srv GreeterServer) {}
+`,
+}
+
+func TestDiscoverEntrypoints(t *testing.T) {
+	tests := []struct {
+		// pkg is the main package below example.com registering the
+		// entrypoints
+		pkg     string
+		imports string
+		main    string
+		decls   string
+		want    []Entrypoint
+	}{
+		{
+			pkg:     "nethttp",
+			imports: `"net/http"`,
+			main:    `http.HandleFunc("/hash", hash)`,
+			decls:   `func hash(w http.ResponseWriter, r *http.Request) {}`,
+			want:    []Entrypoint{{Kind: EntrypointHTTP, Framework: "net/http", Route: "/hash", Function: "example.com/nethttp.hash"}},
+		},
+		{
+			// Go 1.22 patterns may start with a method
+			pkg:     "servemux",
+			imports: `"net/http"`,
+			main:    `mux := http.NewServeMux(); mux.HandleFunc("POST /items/{id}", item)`,
+			decls:   `func item(w http.ResponseWriter, r *http.Request) {}`,
+			want:    []Entrypoint{{Kind: EntrypointHTTP, Framework: "net/http", Methods: []string{"POST"}, Route: "/items/{id}", Function: "example.com/servemux.item"}},
+		},
+		{
+			pkg:     "handler",
+			imports: `"net/http"`,
+			main:    `http.Handle("/api", &api{})`,
+			decls:   `type api struct{}; func (*api) ServeHTTP(w http.ResponseWriter, r *http.Request) {}`,
+			want:    []Entrypoint{{Kind: EntrypointHTTP, Framework: "net/http", Route: "/api", Function: "(*example.com/handler.api).ServeHTTP"}},
+		},
+		{
+			pkg:     "closure",
+			imports: `"net/http"`,
+			main:    `health := func(w http.ResponseWriter, r *http.Request) {}; http.Handle("/health", http.HandlerFunc(health))`,
+			want:    []Entrypoint{{Kind: EntrypointHTTP, Framework: "net/http", Route: "/health", Function: "example.com/closure.main$health"}},
+		},
+		{
+			// Handlers returned by a call are not statically known
+			pkg:     "dynamic",
+			imports: `"net/http"`,
+			main:    `http.HandleFunc("/dynamic", handler())`,
+			decls:   `func handler() http.HandlerFunc { return nil }`,
+		},
+		{
+			pkg:     "mux",
+			imports: `"net/http"; "github.com/gorilla/mux"`,
+			main:    `r := mux.NewRouter(); api := r.PathPrefix("/api").Subrouter(); api.HandleFunc("/users", users).Methods("GET", "POST")`,
+			decls:   `func users(w http.ResponseWriter, r *http.Request) {}`,
+			want:    []Entrypoint{{Kind: EntrypointHTTP, Framework: "gorilla/mux", Methods: []string{"GET", "POST"}, Route: "/api/users", Function: "example.com/mux.users"}},
+		},
+		{
+			pkg:     "gin",
+			imports: `"github.com/gin-gonic/gin"`,
+			main:    `r := gin.Default(); v1 := r.Group("/v1"); v1.GET("/ping", auth, ping); r.Handle("DELETE", "/items", ping); r.Any("/any", ping)`,
+			decls:   `func auth(c *gin.Context) {}; func ping(c *gin.Context) {}`,
+			want: []Entrypoint{
+				{Kind: EntrypointHTTP, Framework: "gin", Route: "/any", Function: "example.com/gin.ping"},
+				{Kind: EntrypointHTTP, Framework: "gin", Methods: []string{"DELETE"}, Route: "/items", Function: "example.com/gin.ping"},
+				{Kind: EntrypointHTTP, Framework: "gin", Methods: []string{"GET"}, Route: "/v1/ping", Function: "example.com/gin.ping"},
+			},
+		},
+		{
+			pkg:     "grpc",
+			imports: `"example.com/pb"`,
+			main:    `pb.RegisterGreeterServer(nil, &server{})`,
+			decls:   `type server struct{ pb.UnimplementedGreeterServer }; func (*server) SayHello() {}`,
+			want:    []Entrypoint{{Kind: EntrypointGRPC, Framework: "grpc", Route: "Greeter/SayHello", Function: "(*example.com/grpc.server).SayHello"}},
+		},
+		{
+			pkg:   "goroutine",
+			main:  `go worker(); go func() {}()`,
+			decls: `func worker() {}`,
+			want: []Entrypoint{
+				{Kind: EntrypointGoroutine, Function: "example.com/goroutine.main$a0b58e38"},
+				{Kind: EntrypointGoroutine, Function: "example.com/goroutine.worker"},
+			},
+		},
+	}
+
+	files := make(map[string]string)
+	for name, content := range entrypointModules {
+		files[name] = content
+	}
+	for _, tt := range tests {
+		files[tt.pkg+"/main.go"] = "package main\n\nimport (" + tt.imports + ")\n\nfunc main() {\n\t" + tt.main + "\n}\n\n" + tt.decls + "\n"
+	}
+	prog, _, _ := testProgram(t, files)
+
+	for _, tt := range tests {
+		t.Run(tt.pkg, func(t *testing.T) {
+			got := DiscoverEntrypoints(prog, func(pkgPath string) bool { return pkgPath == "example.com/"+tt.pkg })
+			for i := range got {
+				// Every registration is on line 6 of main.go
+				if filepath.Base(got[i].File) != "main.go" || got[i].Line != 6 {
+					t.Errorf("entrypoint %s registered at %s:%d, want main.go:6", got[i].Function, got[i].File, got[i].Line)
+				}
+				got[i].File, got[i].Line, got[i].fn = "", 0, nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiscoverEntrypoints =\n  %+v\nwant\n  %+v", got, tt.want)
+			}
+		})
+	}
+}
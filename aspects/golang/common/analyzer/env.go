@@ -0,0 +1,183 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// hostGoVariables are the variables of the analyzer's environment passed on
+// to the go command: where it keeps its caches and temporary files.
+var hostGoVariables = []string{"HOME", "XDG_CACHE_HOME", "GOCACHE", "GOMODCACHE", "GOPATH", "TMPDIR"}
+
+// setupGoEnvironment checks the Go SDK of the configuration and makes its
+// root absolute, since the go command may run in another directory.
+func setupGoEnvironment(cfg *Config) error {
+	if cfg.GoRoot == "" {
+		return nil
+	}
+	goroot, err := filepath.Abs(cfg.GoRoot)
+	if err != nil {
+		return fmt.Errorf("invalid Go SDK %s: %v", cfg.GoRoot, err)
+	}
+	if _, err := os.Stat(filepath.Join(goroot, "bin", "go")); err != nil {
+		return fmt.Errorf("Go SDK %s has no go command: %v", goroot, err)
+	}
+	cfg.GoRoot = goroot
+	return nil
+}
+
+// checkGoCommand verifies that the go command the loaders run belongs to the
+// Go SDK of the configuration. go/packages runs the first go command on the
+// PATH of the analyzer process, whatever environment it passes to it, so
+// the caller must put the bin directory of the SDK first on the PATH.
+func checkGoCommand(cfg *Config) error {
+	if cfg.GoRoot == "" {
+		return nil
+	}
+	goBin := filepath.Join(cfg.GoRoot, "bin")
+	path, err := exec.LookPath("go")
+	if err != nil {
+		return fmt.Errorf("no usable go command on the PATH (%v); put %s first on the PATH", err, goBin)
+	}
+	found, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("no usable go command on the PATH (%v); put %s first on the PATH", err, goBin)
+	}
+	want, err := os.Stat(filepath.Join(goBin, "go"))
+	if err != nil || !os.SameFile(found, want) {
+		return fmt.Errorf("the go command on the PATH, %s, is not the one of the Go SDK %s; put %s first on the PATH", path, cfg.GoRoot, goBin)
+	}
+	return nil
+}
+
+// buildGoEnvironment returns the environment of the go commands run in dir.
+// It is built from the Go SDK and target platform of the configuration,
+// with only the cache locations taken from the analyzer's environment: the
+// user's go env file is ignored, toolchain switching disabled and no
+// gopackagesdriver run, so only the flags of the action decide what is
+// loaded. Cgo is enabled only when the configuration says the target is
+// built with it. Outside a module, module mode is turned off rather than a
+// go.mod created.
+func buildGoEnvironment(cfg *Config, dir string) []string {
+	env := []string{"GOENV=off", "GOTOOLCHAIN=local", "GOFLAGS=", "GOPACKAGESDRIVER=off"}
+	if cfg.Cgo {
+		env = append(env, "CGO_ENABLED=1")
+	} else {
+		env = append(env, "CGO_ENABLED=0")
+	}
+	for _, name := range hostGoVariables {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	if moduleRoot(dir) != "" {
+		env = append(env, "GO111MODULE=on")
+	} else {
+		env = append(env, "GO111MODULE=off")
+	}
+	if cfg.GoRoot != "" {
+		env = append(env, "GOROOT="+cfg.GoRoot)
+	}
+	if cfg.GOOS != "" {
+		env = append(env, "GOOS="+cfg.GOOS)
+	}
+	if cfg.GOARCH != "" {
+		env = append(env, "GOARCH="+cfg.GOARCH)
+	}
+	return env
+}
+
+// buildFlags returns the go build flags selecting the build tags of the
+// configuration.
+func buildFlags(cfg *Config) []string {
+	if len(cfg.Tags) == 0 {
+		return nil
+	}
+	return []string{"-tags=" + strings.Join(cfg.Tags, ",")}
+}
+
+// moduleRoot returns the directory holding the go.mod of the module dir
+// belongs to, or "" when dir is not in a module.
+func moduleRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// workspaceEnvironment returns the environment and build flags that isolate
+// a go command run in the workspace from other runs and from the network:
+//
+//   - the Go SDK, platform and build tags are those of the configuration;
+//   - modules are resolved from vendor/ when the workspace vendors them, and
+//     from the module cache otherwise; GOPROXY=off forbids downloads unless
+//     a proxy is configured explicitly, in which case it and the private
+//     module settings are passed on, and -mod=readonly keeps the go
+//     command from rewriting the go.mod and go.sum of the workspace, so a
+//     module missing from the cache is reported rather than added;
+//   - the build cache is private to the run unless GOCACHE is set, so
+//     parallel actions do not share state;
+//   - go.work files and toolchain switching are ignored.
+//
+// The returned cleanup removes the private build cache.
+func workspaceEnvironment(cfg *Config, workspaceRoot string) (env, flags []string, cleanup func(), err error) {
+	env = append(buildGoEnvironment(cfg, workspaceRoot), "GOWORK=off")
+	cleanup = func() {}
+
+	if os.Getenv("GOPROXY") == "" {
+		env = append(env, "GOPROXY=off")
+	} else {
+		for _, name := range []string{"GOPROXY", "GOPRIVATE", "GONOPROXY", "GONOSUMDB", "GOSUMDB", "GOINSECURE"} {
+			if value, ok := os.LookupEnv(name); ok {
+				env = append(env, name+"="+value)
+			}
+		}
+	}
+
+	if os.Getenv("GOCACHE") == "" {
+		cache, err := os.MkdirTemp("", "callgraph-gocache-")
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create build cache: %v", err)
+		}
+		env = append(env, "GOCACHE="+cache)
+		cleanup = func() { os.RemoveAll(cache) }
+	}
+
+	if _, err := os.Stat(filepath.Join(workspaceRoot, "vendor", "modules.txt")); err == nil {
+		flags = []string{"-mod=vendor"}
+	} else {
+		flags = []string{"-mod=readonly"}
+	}
+	return env, append(flags, buildFlags(cfg)...), cleanup, nil
+}
+
+func findGoFiles(dir string) ([]string, error) {
+	var goFiles []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if strings.HasSuffix(path, ".go") && !strings.Contains(path, "vendor/") {
+			goFiles = append(goFiles, path)
+		}
+
+		return nil
+	})
+
+	return goFiles, err
+}
@@ -0,0 +1,282 @@
+package analyzer
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ExportFormat is a graph rendering format.
+type ExportFormat string
+
+const (
+	// FormatDOT renders a Graphviz digraph.
+	FormatDOT ExportFormat = "dot"
+	// FormatGraphML renders a GraphML document.
+	FormatGraphML ExportFormat = "graphml"
+	// FormatMermaid renders a Mermaid flowchart.
+	FormatMermaid ExportFormat = "mermaid"
+)
+
+// ParseExportFormat validates an export format name.
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch f := ExportFormat(strings.ToLower(s)); f {
+	case FormatDOT, FormatGraphML, FormatMermaid:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (want dot, graphml or mermaid)", s)
+	}
+}
+
+// ExportOptions selects the part of a call graph to render.
+type ExportOptions struct {
+	Format ExportFormat
+	// Packages collapses functions to their packages; edge weights count
+	// the call sites between two packages.
+	Packages bool
+	// Origins collapses generic instances to their generic origin.
+	Origins bool
+	// Prefixes keeps only functions of packages matching one of the import
+	// path prefixes. Empty keeps all packages.
+	Prefixes []string
+	// Root is a function ID, the label of a discovered entrypoint such as
+	// "GET /hash/{data}", or a package import path to start from. Only
+	// nodes reachable from it through kept nodes are rendered.
+	Root string
+	// Depth limits the number of calls from the root; zero is unlimited.
+	Depth int
+}
+
+type exportNode struct {
+	ID      string
+	Label   string
+	Package string
+}
+
+type exportEdge struct {
+	From, To string
+	Weight   int
+}
+
+type exportGraph struct {
+	nodes []exportNode
+	edges []exportEdge
+}
+
+// Export renders the call graph of the result in the chosen format.
+func Export(w io.Writer, result *CallGraphResult, opts ExportOptions) error {
+	if opts.Origins {
+		result = CollapseGenerics(result)
+	}
+	graph, err := buildExportGraph(result, opts)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	switch opts.Format {
+	case FormatDOT:
+		writeDOT(bw, graph)
+	case FormatGraphML:
+		writeGraphML(bw, graph)
+	case FormatMermaid:
+		writeMermaid(bw, graph)
+	default:
+		return fmt.Errorf("unknown export format %q", opts.Format)
+	}
+	return bw.Flush()
+}
+
+// buildExportGraph applies the filters of the options to the result and
+// collapses it to packages if requested.
+func buildExportGraph(result *CallGraphResult, opts ExportOptions) (*exportGraph, error) {
+	kept := func(id string) bool {
+		return len(opts.Prefixes) == 0 || matchesPackage(functionPackage(result, id), opts.Prefixes)
+	}
+
+	// Select the functions to render
+	selected := make(map[string]bool)
+	if opts.Root == "" {
+		for caller, callees := range result.CallGraph {
+			if kept(caller) {
+				selected[caller] = true
+			}
+			for _, callee := range callees {
+				if kept(callee) {
+					selected[callee] = true
+				}
+			}
+		}
+	} else {
+		var frontier []string
+		if _, ok := result.Functions[opts.Root]; ok {
+			frontier = []string{opts.Root}
+		} else if fn := entrypointFunction(result, opts.Root); fn != "" {
+			frontier = []string{fn}
+		} else {
+			for id := range result.Functions {
+				if matchesPackage(functionPackage(result, id), []string{opts.Root}) {
+					frontier = append(frontier, id)
+				}
+			}
+		}
+		if len(frontier) == 0 {
+			return nil, fmt.Errorf("root %q is neither a function ID, an entrypoint route nor a package in the result", opts.Root)
+		}
+		for _, id := range frontier {
+			selected[id] = true
+		}
+		for depth := 0; len(frontier) > 0 && (opts.Depth == 0 || depth < opts.Depth); depth++ {
+			var next []string
+			for _, caller := range frontier {
+				for _, callee := range result.CallGraph[caller] {
+					if !selected[callee] && kept(callee) {
+						selected[callee] = true
+						next = append(next, callee)
+					}
+				}
+			}
+			frontier = next
+		}
+	}
+
+	// Map functions to rendered nodes and count call sites between them
+	nodes := make(map[string]exportNode)
+	weights := make(map[EdgeKey]int)
+	nodeOf := func(id string) string {
+		pkg := functionPackage(result, id)
+		if opts.Packages {
+			nodes[pkg] = exportNode{ID: pkg, Label: pkg, Package: pkg}
+			return pkg
+		}
+		nodes[id] = exportNode{ID: id, Label: shortFunctionLabel(id, pkg), Package: pkg}
+		return id
+	}
+	for caller := range selected {
+		from := nodeOf(caller)
+		for _, callee := range result.CallGraph[caller] {
+			if !selected[callee] {
+				continue
+			}
+			to := nodeOf(callee)
+			if opts.Packages && from == to {
+				continue
+			}
+			weights[EdgeKey{from, to}]++
+		}
+	}
+
+	graph := &exportGraph{}
+	for _, node := range nodes {
+		graph.nodes = append(graph.nodes, node)
+	}
+	sort.Slice(graph.nodes, func(i, j int) bool { return graph.nodes[i].ID < graph.nodes[j].ID })
+	for edge, weight := range weights {
+		graph.edges = append(graph.edges, exportEdge{From: edge.Caller, To: edge.Callee, Weight: weight})
+	}
+	sort.Slice(graph.edges, func(i, j int) bool {
+		if graph.edges[i].From != graph.edges[j].From {
+			return graph.edges[i].From < graph.edges[j].From
+		}
+		return graph.edges[i].To < graph.edges[j].To
+	})
+	return graph, nil
+}
+
+// entrypointFunction returns the function of the discovered entrypoint with
+// the given label, or "".
+func entrypointFunction(result *CallGraphResult, label string) string {
+	for _, e := range result.DiscoveredEntrypoints {
+		if e.Label() == label {
+			return e.Function
+		}
+	}
+	return ""
+}
+
+// functionPackage returns the package of a function ID in the result.
+func functionPackage(result *CallGraphResult, id string) string {
+	if info, ok := result.Functions[id]; ok && info.Package != "" {
+		return info.Package
+	}
+	return "builtin"
+}
+
+// shortFunctionLabel abbreviates the function's own package path to its last
+// element, e.g. (*github.com/x/web.Server).Start to (*web.Server).Start.
+func shortFunctionLabel(id, pkg string) string {
+	if pkg == "" || pkg == "builtin" {
+		return id
+	}
+	return strings.ReplaceAll(id, pkg+".", path.Base(pkg)+".")
+}
+
+func writeDOT(w io.Writer, graph *exportGraph) {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+	fmt.Fprintln(w, "digraph callgraph {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for _, node := range graph.nodes {
+		fmt.Fprintf(w, "  %s [label=%s];\n", quote(node.ID), quote(node.Label))
+	}
+	for _, edge := range graph.edges {
+		if edge.Weight > 1 {
+			fmt.Fprintf(w, "  %s -> %s [label=\"%d\"];\n", quote(edge.From), quote(edge.To), edge.Weight)
+		} else {
+			fmt.Fprintf(w, "  %s -> %s;\n", quote(edge.From), quote(edge.To))
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func writeGraphML(w io.Writer, graph *exportGraph) {
+	escape := func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(w, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(w, `  <key id="label" for="node" attr.name="label" attr.type="string"/>`)
+	fmt.Fprintln(w, `  <key id="package" for="node" attr.name="package" attr.type="string"/>`)
+	fmt.Fprintln(w, `  <key id="weight" for="edge" attr.name="weight" attr.type="int"/>`)
+	fmt.Fprintln(w, `  <graph id="callgraph" edgedefault="directed">`)
+	for _, node := range graph.nodes {
+		fmt.Fprintf(w, "    <node id=\"%s\">\n", escape(node.ID))
+		fmt.Fprintf(w, "      <data key=\"label\">%s</data>\n", escape(node.Label))
+		fmt.Fprintf(w, "      <data key=\"package\">%s</data>\n", escape(node.Package))
+		fmt.Fprintln(w, "    </node>")
+	}
+	for _, edge := range graph.edges {
+		fmt.Fprintf(w, "    <edge source=\"%s\" target=\"%s\">\n", escape(edge.From), escape(edge.To))
+		fmt.Fprintf(w, "      <data key=\"weight\">%d</data>\n", edge.Weight)
+		fmt.Fprintln(w, "    </edge>")
+	}
+	fmt.Fprintln(w, "  </graph>")
+	fmt.Fprintln(w, "</graphml>")
+}
+
+func writeMermaid(w io.Writer, graph *exportGraph) {
+	// Mermaid node IDs must be simple identifiers, so number the nodes and
+	// put the function or package name in the label
+	ids := make(map[string]string, len(graph.nodes))
+	label := strings.NewReplacer(`"`, "#quot;")
+	fmt.Fprintln(w, "flowchart LR")
+	for i, node := range graph.nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(w, "    %s[\"%s\"]\n", ids[node.ID], label.Replace(node.Label))
+	}
+	for _, edge := range graph.edges {
+		if edge.Weight > 1 {
+			fmt.Fprintf(w, "    %s -->|%d| %s\n", ids[edge.From], edge.Weight, ids[edge.To])
+		} else {
+			fmt.Fprintf(w, "    %s --> %s\n", ids[edge.From], ids[edge.To])
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"go/version"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/gcexportdata"
+	"golang.org/x/tools/go/packages"
+)
+
+// stdlibSourceID prefixes the IDs of the standard library packages the
+// export data loader type-checks from the sources of the Go SDK.
+const stdlibSourceID = "@rules_go//stdlib:"
+
+// exportDataLoader builds packages from the packages JSON alone: dependency
+// types come from the export files compiled by rules_go and only the root
+// packages are type-checked from source, so no go command is run. Packages
+// the JSON lists without imports have them read from their Go files, and
+// standard library packages missing from the JSON are type-checked from
+// the sources of the Go SDK, since the GoArchive of a target lists no
+// export files for them and Go SDKs ship no precompiled standard library.
+type exportDataLoader struct {
+	cfg   *Config
+	fset  *token.FileSet
+	sizes types.Sizes
+	// ctxt selects the Go files of the target platform and build tags
+	ctxt *build.Context
+
+	byID   map[string]*PackageJSON
+	byPath map[string]*PackageJSON
+	roots  map[string]bool
+	// loaded holds the packages by ID once their types are available;
+	// loading marks packages in progress to detect import cycles.
+	loaded  map[string]*packages.Package
+	loading map[string]bool
+}
+
+// loadExportData loads the root packages of the response from source and
+// every other package from its export file.
+func loadExportData(cfg *Config, response *PackagesResponse) ([]*packages.Package, error) {
+	compiler, arch := response.Compiler, response.Arch
+	if compiler == "" {
+		compiler = "gc"
+	}
+	// The platform passed by the aspect is authoritative
+	if cfg.GOARCH != "" {
+		arch = cfg.GOARCH
+	}
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+
+	l := &exportDataLoader{
+		cfg:     cfg,
+		fset:    token.NewFileSet(),
+		sizes:   types.SizesFor(compiler, arch),
+		ctxt:    buildContext(cfg, arch),
+		byID:    make(map[string]*PackageJSON),
+		byPath:  make(map[string]*PackageJSON),
+		roots:   make(map[string]bool),
+		loaded:  make(map[string]*packages.Package),
+		loading: make(map[string]bool),
+	}
+	for _, pkg := range response.Packages {
+		l.byID[pkg.ID] = pkg
+		// Test variants share the import path of the package they extend
+		if prev := l.byPath[pkg.PkgPath]; prev == nil || strings.Contains(prev.ID, " [") {
+			l.byPath[pkg.PkgPath] = pkg
+		}
+	}
+
+	// Roots are the requested workspace packages, or every workspace package
+	// with sources when the response names none
+	for _, id := range response.Roots {
+		if pkg := l.byID[id]; pkg != nil && IsSourcePackage(id) && len(pkg.GoFiles) > 0 {
+			l.roots[id] = true
+		}
+	}
+	if len(l.roots) == 0 {
+		for _, pkg := range response.SourcePackages() {
+			if len(pkg.GoFiles) > 0 {
+				l.roots[pkg.ID] = true
+			}
+		}
+	}
+	if len(l.roots) == 0 {
+		return nil, fmt.Errorf("no root packages with Go files in the packages JSON")
+	}
+
+	var pkgs []*packages.Package
+	for _, pkg := range response.Packages {
+		if !l.roots[pkg.ID] {
+			continue
+		}
+		loaded, err := l.load(pkg.ID)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, loaded)
+	}
+	cfg.logf("📦 Loaded %d root packages from source and %d packages from export data\n", len(pkgs), len(l.loaded)-len(pkgs))
+	return pkgs, nil
+}
+
+// load returns the package with the given ID, loading its dependencies first.
+func (l *exportDataLoader) load(id string) (*packages.Package, error) {
+	if pkg, ok := l.loaded[id]; ok {
+		return pkg, nil
+	}
+	jsonPkg := l.byID[id]
+	if jsonPkg == nil {
+		return nil, fmt.Errorf("package %s is not in the packages JSON", id)
+	}
+	if l.loading[id] {
+		return nil, fmt.Errorf("import cycle through %s", id)
+	}
+	l.loading[id] = true
+	defer delete(l.loading, id)
+
+	pkg := &packages.Package{
+		ID:              jsonPkg.ID,
+		Name:            jsonPkg.Name,
+		PkgPath:         jsonPkg.PkgPath,
+		GoFiles:         jsonPkg.GoFiles,
+		CompiledGoFiles: jsonPkg.CompiledGoFiles,
+		ExportFile:      jsonPkg.ExportFile,
+		Imports:         make(map[string]*packages.Package),
+		Fset:            l.fset,
+		TypesSizes:      l.sizes,
+	}
+
+	var err error
+	switch {
+	case pkg.PkgPath == "unsafe":
+		pkg.Types = types.Unsafe
+	case l.roots[id] || jsonPkg.ExportFile == "" && IsSourcePackage(id) && len(jsonPkg.GoFiles) > 0:
+		if pkg.Syntax, err = l.parseFiles(sourceFiles(pkg), parser.ParseComments); err != nil {
+			return nil, err
+		}
+		if err = l.loadImports(pkg, jsonPkg.Imports, importPaths(pkg.Syntax)); err != nil {
+			return nil, err
+		}
+		l.checkSource(pkg)
+	case jsonPkg.ExportFile == "" && IsStdlibPackage(id):
+		// Drivers list the standard library without export data; its types
+		// come from the sources of the Go SDK instead
+		std, err := l.loadStdlib(pkg.PkgPath, false)
+		if err != nil {
+			return nil, err
+		}
+		if std == nil {
+			return nil, fmt.Errorf("no export file for %s (%s) and no Go SDK to load it from; pass --goroot", pkg.PkgPath, pkg.ID)
+		}
+		pkg = std
+	default:
+		// Without listed imports, the dependencies are those of the Go files
+		var paths []string
+		if len(jsonPkg.Imports) == 0 {
+			files, err := l.parseFiles(sourceFiles(pkg), parser.ImportsOnly)
+			if err != nil {
+				return nil, err
+			}
+			paths = importPaths(files)
+		}
+		if err = l.loadImports(pkg, jsonPkg.Imports, paths); err != nil {
+			return nil, err
+		}
+		if err = l.readExportData(pkg); err != nil {
+			return nil, err
+		}
+	}
+	// Packages JSON written by the aspects may lack the package names
+	if pkg.Types != nil {
+		pkg.Name = pkg.Types.Name()
+	}
+	l.loaded[id] = pkg
+	return pkg, nil
+}
+
+// loadImports loads the imports listed by the packages JSON, keyed by
+// import path, and then the given import paths it does not list. These are
+// looked up by import path in the packages JSON, and otherwise in the
+// standard library of the Go SDK. Imports found in neither are left out:
+// type-checking reports them for source packages.
+func (l *exportDataLoader) loadImports(pkg *packages.Package, listed map[string]string, paths []string) error {
+	for importPath, importID := range listed {
+		dep, err := l.load(importID)
+		if err != nil {
+			return err
+		}
+		pkg.Imports[importPath] = dep
+	}
+
+	fromStd := IsStdlibPackage(pkg.ID)
+	for _, path := range paths {
+		if _, ok := pkg.Imports[path]; ok || path == "unsafe" || path == "C" {
+			continue
+		}
+		var dep *packages.Package
+		var err error
+		// The standard library only imports itself and its vendored packages
+		if jsonPkg := l.byPath[path]; jsonPkg != nil && jsonPkg.ID != pkg.ID && (!fromStd || IsStdlibPackage(jsonPkg.ID)) {
+			dep, err = l.load(jsonPkg.ID)
+		} else {
+			dep, err = l.loadStdlib(path, fromStd)
+		}
+		if err != nil {
+			return err
+		}
+		if dep != nil {
+			pkg.Imports[path] = dep
+		}
+	}
+	return nil
+}
+
+// loadStdlib type-checks the standard library package with the given
+// import path from the sources of the Go SDK, ignoring function bodies.
+// Imports from the standard library itself may resolve to the packages
+// vendored in GOROOT/src/vendor. It returns nil when there is no Go SDK or
+// the package is not part of it.
+func (l *exportDataLoader) loadStdlib(path string, fromStd bool) (*packages.Package, error) {
+	if l.cfg.GoRoot == "" {
+		return nil, nil
+	}
+	pkgPath := path
+	dir := filepath.Join(l.cfg.GoRoot, "src", filepath.FromSlash(path))
+	if info, err := os.Stat(dir); fromStd && (err != nil || !info.IsDir()) {
+		pkgPath = "vendor/" + path
+		dir = filepath.Join(l.cfg.GoRoot, "src", "vendor", filepath.FromSlash(path))
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+
+	id := stdlibSourceID + pkgPath
+	if pkg, ok := l.loaded[id]; ok {
+		return pkg, nil
+	}
+	if l.loading[id] {
+		return nil, fmt.Errorf("import cycle through %s", id)
+	}
+	l.loading[id] = true
+	defer delete(l.loading, id)
+
+	bp, err := l.ctxt.ImportDir(dir, 0)
+	if err != nil {
+		if _, ok := err.(*build.NoGoError); ok {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load %s from the Go SDK: %v", pkgPath, err)
+	}
+	pkg := &packages.Package{
+		ID:         id,
+		Name:       bp.Name,
+		PkgPath:    pkgPath,
+		Imports:    make(map[string]*packages.Package),
+		Fset:       l.fset,
+		TypesSizes: l.sizes,
+	}
+	for _, name := range bp.GoFiles {
+		pkg.GoFiles = append(pkg.GoFiles, filepath.Join(dir, name))
+	}
+	files, err := l.parseFiles(pkg.GoFiles, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.loadImports(pkg, nil, bp.Imports); err != nil {
+		return nil, err
+	}
+
+	// Like export data, the package only provides the declarations its
+	// importers see, so type errors are reported but do not drop it
+	conf := l.typesConfig(pkg)
+	conf.IgnoreFuncBodies = true
+	pkg.Types, _ = conf.Check(pkg.PkgPath, l.fset, files, nil)
+	l.loaded[id] = pkg
+	return pkg, nil
+}
+
+// readExportData reads the types of the package from its export file. The
+// types of its transitive dependencies, already loaded, are shared so that
+// objects have a single identity across packages.
+func (l *exportDataLoader) readExportData(pkg *packages.Package) error {
+	if pkg.ExportFile == "" {
+		return fmt.Errorf("no export file for %s (%s)", pkg.PkgPath, pkg.ID)
+	}
+	file, err := os.Open(pkg.ExportFile)
+	if err != nil {
+		return fmt.Errorf("failed to open export data of %s: %v", pkg.PkgPath, err)
+	}
+	defer file.Close()
+
+	reader, err := gcexportdata.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read export data of %s: %v", pkg.PkgPath, err)
+	}
+
+	view := make(map[string]*types.Package)
+	packages.Visit([]*packages.Package{pkg}, nil, func(dep *packages.Package) {
+		if dep != pkg && dep.Types != nil {
+			view[dep.PkgPath] = dep.Types
+		}
+	})
+	pkg.Types, err = gcexportdata.Read(reader, l.fset, view, pkg.PkgPath)
+	if err != nil {
+		return fmt.Errorf("failed to decode export data of %s: %v", pkg.PkgPath, err)
+	}
+	return nil
+}
+
+// checkSource type-checks the parsed Go files of the package. Type errors
+// are recorded on the package rather than failing the load.
+func (l *exportDataLoader) checkSource(pkg *packages.Package) {
+	pkg.TypesInfo = &types.Info{
+		Types:        make(map[ast.Expr]types.TypeAndValue),
+		Defs:         make(map[*ast.Ident]types.Object),
+		Uses:         make(map[*ast.Ident]types.Object),
+		Implicits:    make(map[ast.Node]types.Object),
+		Instances:    make(map[*ast.Ident]types.Instance),
+		Scopes:       make(map[ast.Node]*types.Scope),
+		Selections:   make(map[*ast.SelectorExpr]*types.Selection),
+		FileVersions: make(map[*ast.File]string),
+	}
+	pkg.Types, _ = l.typesConfig(pkg).Check(pkg.PkgPath, l.fset, pkg.Syntax, pkg.TypesInfo)
+	pkg.IllTyped = len(pkg.Errors) > 0
+}
+
+// typesConfig returns the type-checker configuration of the package, which
+// imports its loaded dependencies and records type errors on it.
+func (l *exportDataLoader) typesConfig(pkg *packages.Package) *types.Config {
+	return &types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if dep, ok := pkg.Imports[path]; ok && dep.Types != nil {
+				return dep.Types, nil
+			}
+			if path == "unsafe" {
+				return types.Unsafe, nil
+			}
+			if l.cfg.GoRoot == "" && !strings.Contains(strings.Split(path, "/")[0], ".") {
+				return nil, fmt.Errorf("package %s imports %s, which is not in the packages JSON; pass --goroot to load the standard library from the Go SDK", pkg.PkgPath, path)
+			}
+			return nil, fmt.Errorf("package %s imports %s, which is not in the packages JSON", pkg.PkgPath, path)
+		}),
+		// Cgo is not preprocessed: references to package C are not checked
+		FakeImportC: l.ctxt.CgoEnabled,
+		Sizes:       l.sizes,
+		Error: func(err error) {
+			if te, ok := err.(types.Error); ok {
+				pkg.Errors = append(pkg.Errors, packages.Error{Pos: l.fset.Position(te.Pos).String(), Msg: te.Msg, Kind: packages.TypeError})
+				return
+			}
+			pkg.Errors = append(pkg.Errors, packages.Error{Msg: err.Error(), Kind: packages.TypeError})
+		},
+	}
+}
+
+// parseFiles parses the Go files that match the target platform and build
+// tags. Bazel lists every source of a target, including those its build
+// constraints exclude.
+func (l *exportDataLoader) parseFiles(filenames []string, mode parser.Mode) ([]*ast.File, error) {
+	var files []*ast.File
+	for _, filename := range filenames {
+		if match, err := l.ctxt.MatchFile(filepath.Dir(filename), filepath.Base(filename)); err == nil && !match {
+			continue
+		}
+		file, err := parser.ParseFile(l.fset, filename, nil, mode|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// sourceFiles returns the Go files the package is compiled from.
+func sourceFiles(pkg *packages.Package) []string {
+	if len(pkg.CompiledGoFiles) > 0 {
+		return pkg.CompiledGoFiles
+	}
+	return pkg.GoFiles
+}
+
+// importPaths returns the import paths of the files, in order of first
+// appearance.
+func importPaths(files []*ast.File) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || seen[path] {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// buildContext returns the build context of the target platform, build
+// tags, cgo setting and Go SDK of the configuration, as for the go commands
+// the loaders run.
+func buildContext(cfg *Config, arch string) *build.Context {
+	ctxt := build.Default
+	ctxt.GOARCH = arch
+	if cfg.GOOS != "" {
+		ctxt.GOOS = cfg.GOOS
+	}
+	if cfg.GoRoot != "" {
+		ctxt.GOROOT = cfg.GoRoot
+		if tags := releaseTags(cfg.GoRoot); tags != nil {
+			ctxt.ReleaseTags = tags
+		}
+	}
+	ctxt.GOPATH = ""
+	ctxt.CgoEnabled = cfg.Cgo
+	ctxt.BuildTags = cfg.Tags
+	return &ctxt
+}
+
+// releaseTags returns the go1.N release tags of the Go SDK, read from its
+// VERSION file, or nil when the version is unknown.
+func releaseTags(goroot string) []string {
+	data, err := os.ReadFile(filepath.Join(goroot, "VERSION"))
+	if err != nil {
+		return nil
+	}
+	lang := version.Lang(strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0]))
+	minor, err := strconv.Atoi(strings.TrimPrefix(lang, "go1."))
+	if err != nil {
+		return nil
+	}
+	var tags []string
+	for i := 1; i <= minor; i++ {
+		tags = append(tags, "go1."+strconv.Itoa(i))
+	}
+	return tags
+}
+
+// importerFunc adapts a function to the types.Importer interface.
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
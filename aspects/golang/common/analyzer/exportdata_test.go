@@ -0,0 +1,201 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/gcexportdata"
+)
+
+// writeExportData type-checks the source of a dependency, writes it to dir
+// as its Go file and its export file and returns the types and the paths of
+// both files.
+func writeExportData(t *testing.T, dir, path, src string, imports map[string]*types.Package) (*types.Package, string, string) {
+	t.Helper()
+	base := filepath.Join(dir, strings.ReplaceAll(path, "/", "_"))
+	goFile, exportFile := base+".go", base+".x"
+	if err := os.WriteFile(goFile, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, goFile, src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importerFunc(func(path string) (*types.Package, error) { return imports[path], nil })}
+	pkg, err := conf.Check(path, fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wrap the export data in the archive the compiler writes
+	var data bytes.Buffer
+	data.WriteString("go object linux amd64\n$$B\n")
+	if err := gcexportdata.Write(&data, fset, pkg); err != nil {
+		t.Fatal(err)
+	}
+	data.WriteString("\n$$\n")
+	archive := fmt.Sprintf("!<arch>\n%-16s%-12s%-6s%-6s%-8s%-10d`\n%s", "__.PKGDEF", "0", "0", "0", "644", data.Len(), data.String())
+	if err := os.WriteFile(exportFile, []byte(archive), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return pkg, goFile, exportFile
+}
+
+func TestLoadExportData(t *testing.T) {
+	dir := t.TempDir()
+	one, oneGo, oneExport := writeExportData(t, dir, "example.com/dep/one", `package one
+
+type T struct{ N int }
+
+func New() T { return T{} }
+`, nil)
+	_, twoGo, twoExport := writeExportData(t, dir, "example.com/dep/two", `package two
+
+import "example.com/dep/one"
+
+func Make() one.T { return one.New() }
+`, map[string]*types.Package{"example.com/dep/one": one})
+
+	// Run only type-checks when two.Make returns the type of the export
+	// data of one; the Windows file would redeclare it
+	appGo := filepath.Join(dir, "app.go")
+	appWindowsGo := filepath.Join(dir, "app_windows.go")
+	files := map[string]string{
+		appGo: `package app
+
+import (
+	"example.com/dep/one"
+	"example.com/dep/two"
+)
+
+func Run() int {
+	var t one.T = two.Make()
+	return t.N
+}
+`,
+		appWindowsGo: `package app
+
+func Run() int { return 0 }
+`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(name, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	response := func() *PackagesResponse {
+		return &PackagesResponse{
+			Roots: []string{"//app:app"},
+			Packages: []*PackageJSON{
+				{
+					ID:      "//app:app",
+					PkgPath: "example.com/app",
+					GoFiles: []string{appGo, appWindowsGo},
+					Imports: map[string]string{"example.com/dep/one": "@dep//one", "example.com/dep/two": "@dep//two"},
+				},
+				{ID: "@dep//one", PkgPath: "example.com/dep/one", GoFiles: []string{oneGo}, ExportFile: oneExport},
+				{
+					ID:         "@dep//two",
+					PkgPath:    "example.com/dep/two",
+					GoFiles:    []string{twoGo},
+					ExportFile: twoExport,
+					Imports:    map[string]string{"example.com/dep/one": "@dep//one"},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *PackagesResponse)
+		// wantErr is part of the load error and wantTypeError part of the
+		// type error of the root package; neither is expected when empty
+		wantErr       string
+		wantTypeError string
+	}{
+		{
+			name:   "listed imports",
+			modify: func(r *PackagesResponse) {},
+		},
+		{
+			name: "imports of the Go files",
+			modify: func(r *PackagesResponse) {
+				r.Packages[0].Imports = nil
+				r.Packages[2].Imports = nil
+			},
+		},
+		{
+			name:   "workspace packages as roots",
+			modify: func(r *PackagesResponse) { r.Roots = nil },
+		},
+		{
+			name:    "missing export file",
+			modify:  func(r *PackagesResponse) { r.Packages[1].ExportFile = "" },
+			wantErr: "no export file for example.com/dep/one",
+		},
+		{
+			name: "import missing from the packages JSON",
+			modify: func(r *PackagesResponse) {
+				r.Packages[0].Imports = nil
+				r.Packages = r.Packages[:2]
+			},
+			wantTypeError: "imports example.com/dep/two, which is not in the packages JSON",
+		},
+		{
+			name:    "no root with Go files",
+			modify:  func(r *PackagesResponse) { r.Packages[0].GoFiles = nil },
+			wantErr: "no root packages with Go files",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := response()
+			tt.modify(r)
+			pkgs, err := loadExportData(&Config{GOOS: "linux", GOARCH: "amd64"}, r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadExportData error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pkgs) != 1 || pkgs[0].ID != "//app:app" {
+				t.Fatalf("loadExportData returned %v, want the root package", pkgs)
+			}
+
+			root := pkgs[0]
+			if tt.wantTypeError != "" {
+				if !root.IllTyped || len(root.Errors) == 0 || !strings.Contains(root.Errors[0].Msg, tt.wantTypeError) {
+					t.Fatalf("root package errors = %v, want one containing %q", root.Errors, tt.wantTypeError)
+				}
+				return
+			}
+			if root.IllTyped {
+				t.Fatalf("root package is ill-typed: %v", root.Errors)
+			}
+			if root.Name != "app" || len(root.Syntax) != 1 || root.TypesInfo == nil {
+				t.Errorf("root package %s has %d files and types info %v, want app from source", root.Name, len(root.Syntax), root.TypesInfo != nil)
+			}
+			dep := root.Imports["example.com/dep/two"]
+			if dep == nil || dep.Types == nil || dep.Syntax != nil {
+				t.Fatalf("dependency two = %+v, want its types from export data", dep)
+			}
+			if dep.Imports["example.com/dep/one"] != root.Imports["example.com/dep/one"] {
+				t.Error("the root and dependency two import different packages one")
+			}
+		})
+	}
+}
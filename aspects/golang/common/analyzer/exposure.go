@@ -0,0 +1,138 @@
+package analyzer
+
+import (
+	"go/types"
+	"slices"
+	"sort"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// DefaultExposurePackages are the security-sensitive standard library
+// packages the exposure report lists by default.
+var DefaultExposurePackages = []string{
+	"crypto", "database/sql", "html/template", "os/exec", "plugin", "syscall", "text/template",
+}
+
+// RouteExposure lists what a route reaches, directly or through other
+// dependencies: the third-party modules and the sensitive standard library
+// packages.
+type RouteExposure struct {
+	// Route is the label of the entrypoint, e.g. "POST /auth/login"
+	Route    string `json:"route"`
+	Kind     string `json:"kind"`
+	Function string `json:"function"`
+	// Modules are the third-party modules with reachable functions
+	Modules []ExposedDependency `json:"modules"`
+	// SensitivePackages are the reachable sensitive stdlib packages
+	SensitivePackages []ExposedDependency `json:"sensitive_packages"`
+}
+
+// ExposedDependency is a module or package reachable from a route, with one
+// shortest call path from the route's handler into it.
+type ExposedDependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	// Packages are the reached packages of a module, such as
+	// golang.org/x/crypto/bcrypt for golang.org/x/crypto
+	Packages []string `json:"packages,omitempty"`
+	Witness  []string `json:"witness"`
+}
+
+// RouteExposures walks the call graph from the handler of every discovered
+// entrypoint with a route and reports the third-party modules and the
+// standard library packages matching the sensitive prefixes it reaches.
+//
+// Packages whose module the resolver does not know are left out of the
+// modules and returned, sorted, with the exposures.
+//
+// The walk follows calls through dependencies too, so a module or package
+// a route only reaches through another one, such as database/sql through
+// an ORM, is reported; each function is visited once per route. Unless
+// followFormatting is set, calls of Error and String through an interface
+// are not followed: formatting a value or an error would otherwise reach
+// every implementation of these methods in the program.
+func RouteExposures(cg *callgraph.Graph, entrypoints []Entrypoint, modules *ModuleResolver, scope *Scope, sensitive []string, followFormatting bool) ([]RouteExposure, []string) {
+	var exposures []RouteExposure
+	var unresolved []string
+	for _, e := range entrypoints {
+		if e.Route == "" || e.fn == nil {
+			continue
+		}
+		exposure := RouteExposure{
+			Route:             e.Label(),
+			Kind:              e.Kind,
+			Function:          e.Function,
+			Modules:           []ExposedDependency{},
+			SensitivePackages: []ExposedDependency{},
+		}
+
+		// The walk is breadth-first, so the first function found in a
+		// module or package gives the shortest witness
+		seenModules := make(map[string]int)
+		seenPackages := make(map[string]bool)
+		var follow func(*callgraph.Edge) bool
+		if !followFormatting {
+			follow = func(edge *callgraph.Edge) bool { return !isFormattingCall(edge) }
+		}
+		walk := walkCallGraphWithin(cg, []*ssa.Function{e.fn}, follow)
+		for _, fn := range walk.order {
+			pkgPath := functionPackagePath(fn)
+			if pkgPath == "" || scope.IsInternal(pkgPath) {
+				continue
+			}
+			if isStandardImportPath(pkgPath) {
+				if !seenPackages[pkgPath] && matchesPackage(pkgPath, sensitive) {
+					seenPackages[pkgPath] = true
+					exposure.SensitivePackages = append(exposure.SensitivePackages, ExposedDependency{
+						Path:    pkgPath,
+						Witness: walk.path(fn),
+					})
+				}
+				continue
+			}
+			module, ok := modules.Module(pkgPath)
+			if !ok {
+				unresolved = append(unresolved, pkgPath)
+				continue
+			}
+			i, ok := seenModules[module]
+			if !ok {
+				i = len(exposure.Modules)
+				seenModules[module] = i
+				exposure.Modules = append(exposure.Modules, ExposedDependency{
+					Path:    module,
+					Version: modules.Version(module),
+					Witness: walk.path(fn),
+				})
+			}
+			if m := &exposure.Modules[i]; !slices.Contains(m.Packages, pkgPath) {
+				m.Packages = append(m.Packages, pkgPath)
+			}
+		}
+
+		for i := range exposure.Modules {
+			sort.Strings(exposure.Modules[i].Packages)
+		}
+		sort.Slice(exposure.Modules, func(i, j int) bool {
+			return exposure.Modules[i].Path < exposure.Modules[j].Path
+		})
+		sort.Slice(exposure.SensitivePackages, func(i, j int) bool {
+			return exposure.SensitivePackages[i].Path < exposure.SensitivePackages[j].Path
+		})
+		exposures = append(exposures, exposure)
+	}
+	return exposures, modules.Unresolved(unresolved)
+}
+
+// isFormattingCall reports whether the edge is an interface call of the
+// Error or String method, as made when formatting errors and values.
+func isFormattingCall(edge *callgraph.Edge) bool {
+	if edge.Site == nil || !edge.Site.Common().IsInvoke() {
+		return false
+	}
+	method := edge.Site.Common().Method
+	return (method.Name() == "Error" || method.Name() == "String") &&
+		method.Type().(*types.Signature).Params().Len() == 0
+}
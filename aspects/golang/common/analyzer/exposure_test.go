@@ -0,0 +1,184 @@
+package analyzer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouteExposures(t *testing.T) {
+	prog, _, cg := testProgram(t, map[string]string{
+		"app/app.go": `package app
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os/exec"
+
+	"example.com/logx"
+	"example.com/orm"
+)
+
+func Register() {
+	http.HandleFunc("/run", run)
+	http.HandleFunc("POST /users", users)
+	http.HandleFunc("/format", format)
+}
+
+func run(w http.ResponseWriter, r *http.Request) { exec.Command("ls") }
+
+func users(w http.ResponseWriter, r *http.Request) {
+	orm.Query()
+	sha256.Sum256(nil)
+}
+
+func format(w http.ResponseWriter, r *http.Request) {
+	var err error = failure{}
+	fmt.Println(err.Error())
+}
+
+type failure struct{}
+
+func (failure) Error() string {
+	logx.Record()
+	return "failure"
+}
+`,
+		"orm/orm.go": `package orm
+
+import (
+	"database/sql"
+
+	"example.com/orm/scan"
+)
+
+func Query() {
+	sql.Open("driver", "dsn")
+	scan.Row()
+}
+`,
+		"orm/scan/scan.go": `package scan
+
+func Row() {}
+`,
+		"logx/logx.go": `package logx
+
+func Record() {}
+`,
+	})
+
+	// Modules come from the dependencies, as for packages loaded from
+	// export data
+	scope := &Scope{internal: map[string]bool{"example.com/app": true}}
+	entrypoints := DiscoverEntrypoints(prog, scope.IsInternal)
+	modules := NewModuleResolver(nil, []Dependency{
+		{Name: "orm", Version: "v1.2.0", ImportPath: "example.com/orm"},
+		{Name: "logx", ImportPath: "example.com/logx"},
+	})
+
+	tests := []struct {
+		name             string
+		route            string
+		sensitive        []string
+		followFormatting bool
+		// modules replace the resolver of the dependencies when set
+		modules        *ModuleResolver
+		wantModules    []ExposedDependency
+		wantSensitive  []ExposedDependency
+		wantUnresolved []string
+	}{
+		{
+			name:          "sensitive package",
+			route:         "/run",
+			sensitive:     DefaultExposurePackages,
+			wantModules:   []ExposedDependency{},
+			wantSensitive: []ExposedDependency{{Path: "os/exec", Witness: []string{"example.com/app.run", "os/exec.Command"}}},
+		},
+		{
+			name:      "through a module",
+			route:     "POST /users",
+			sensitive: DefaultExposurePackages,
+			wantModules: []ExposedDependency{{
+				Path:     "example.com/orm",
+				Version:  "v1.2.0",
+				Packages: []string{"example.com/orm", "example.com/orm/scan"},
+				Witness:  []string{"example.com/app.users", "example.com/orm.Query"},
+			}},
+			wantSensitive: []ExposedDependency{
+				{Path: "crypto/sha256", Witness: []string{"example.com/app.users", "crypto/sha256.Sum256"}},
+				{Path: "database/sql", Witness: []string{"example.com/app.users", "example.com/orm.Query", "database/sql.Open"}},
+			},
+		},
+		{
+			name:      "custom sensitive packages",
+			route:     "POST /users",
+			sensitive: []string{"crypto"},
+			wantModules: []ExposedDependency{{
+				Path:     "example.com/orm",
+				Version:  "v1.2.0",
+				Packages: []string{"example.com/orm", "example.com/orm/scan"},
+				Witness:  []string{"example.com/app.users", "example.com/orm.Query"},
+			}},
+			wantSensitive: []ExposedDependency{{Path: "crypto/sha256", Witness: []string{"example.com/app.users", "crypto/sha256.Sum256"}}},
+		},
+		{
+			name:          "formatting calls skipped",
+			route:         "/format",
+			sensitive:     DefaultExposurePackages,
+			wantModules:   []ExposedDependency{},
+			wantSensitive: []ExposedDependency{},
+		},
+		{
+			name:             "formatting calls followed",
+			route:            "/format",
+			sensitive:        DefaultExposurePackages,
+			followFormatting: true,
+			wantModules: []ExposedDependency{{
+				Path:     "example.com/logx",
+				Packages: []string{"example.com/logx"},
+				Witness:  []string{"example.com/app.format", "(example.com/app.failure).Error", "example.com/logx.Record"},
+			}},
+			wantSensitive: []ExposedDependency{},
+		},
+		{
+			name:           "unknown module",
+			route:          "POST /users",
+			sensitive:      []string{"crypto"},
+			modules:        NewModuleResolver(nil, []Dependency{{Name: "logx", ImportPath: "example.com/logx"}}),
+			wantModules:    []ExposedDependency{},
+			wantSensitive:  []ExposedDependency{{Path: "crypto/sha256", Witness: []string{"example.com/app.users", "crypto/sha256.Sum256"}}},
+			wantUnresolved: []string{"example.com/orm", "example.com/orm/scan"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := modules
+			if tt.modules != nil {
+				resolver = tt.modules
+			}
+			exposures, unresolved := RouteExposures(cg, entrypoints, resolver, scope, tt.sensitive, tt.followFormatting)
+			if !reflect.DeepEqual(unresolved, tt.wantUnresolved) {
+				t.Errorf("unresolved packages = %q, want %q", unresolved, tt.wantUnresolved)
+			}
+			var got *RouteExposure
+			for _, exposure := range exposures {
+				if exposure.Route == tt.route {
+					got = &exposure
+				}
+			}
+			if got == nil {
+				t.Fatalf("no exposure of route %q", tt.route)
+			}
+			if !strings.HasPrefix(got.Function, "example.com/app.") || got.Kind != EntrypointHTTP {
+				t.Errorf("route %q is served by %s entrypoint %s", tt.route, got.Kind, got.Function)
+			}
+			if !reflect.DeepEqual(got.Modules, tt.wantModules) {
+				t.Errorf("modules =\n  %+v\nwant\n  %+v", got.Modules, tt.wantModules)
+			}
+			if !reflect.DeepEqual(got.SensitivePackages, tt.wantSensitive) {
+				t.Errorf("sensitive packages =\n  %+v\nwant\n  %+v", got.SensitivePackages, tt.wantSensitive)
+			}
+		})
+	}
+}
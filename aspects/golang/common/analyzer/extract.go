@@ -0,0 +1,222 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Extract converts a call graph into the result's call_graph, functions and
+// call_edges sections, keeping the calls made from the packages selected by
+// the scope and tagging each edge as internal or external. Callers and their
+// edges are sorted, so the output does not depend on map order.
+func Extract(cg *callgraph.Graph, result *CallGraphResult, scope *Scope) {
+	totalEdges := 0
+
+	type caller struct {
+		id   string
+		node *callgraph.Node
+	}
+	var callers []caller
+	for fn, node := range cg.Nodes {
+		if fn == nil || node == nil {
+			continue
+		}
+
+		// Skip callers outside the selected packages
+		if !scope.Keeps(functionPackagePath(fn)) {
+			continue
+		}
+		callers = append(callers, caller{FunctionID(fn), node})
+	}
+	sort.Slice(callers, func(i, j int) bool {
+		if callers[i].id != callers[j].id {
+			return callers[i].id < callers[j].id
+		}
+		return callers[i].node.ID < callers[j].node.ID
+	})
+
+	for _, c := range callers {
+		callerInfo := addFunction(result, c.node.Func)
+
+		var edges []NormalizedEdge
+		for _, edge := range c.node.Out {
+			if edge == nil || edge.Callee == nil || edge.Callee.Func == nil {
+				continue
+			}
+
+			calleeInfo := addFunction(result, edge.Callee.Func)
+
+			callEdge := CallEdge{
+				Caller: callerInfo,
+				Callee: calleeInfo,
+				Scope:  scope.EdgeScope(callerInfo.Package, calleeInfo.Package),
+			}
+			setCallSite(&callEdge, edge)
+			edges = append(edges, normalizeEdge(callEdge))
+		}
+		sortEdges(edges)
+
+		var callees []string
+		for _, edge := range edges {
+			callees = append(callees, edge.Callee)
+		}
+		result.CallEdges = append(result.CallEdges, edges...)
+		totalEdges += len(edges)
+
+		if len(callees) > 0 {
+			result.CallGraph[callerInfo.ID] = callees
+		}
+	}
+
+	result.TotalFuncs = len(result.CallGraph)
+	result.TotalEdges = totalEdges
+}
+
+// sortEdges sorts edges by caller, callee and call site.
+func sortEdges(edges []NormalizedEdge) {
+	sort.SliceStable(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		switch {
+		case a.Caller != b.Caller:
+			return a.Caller < b.Caller
+		case a.Callee != b.Callee:
+			return a.Callee < b.Callee
+		case a.File != b.File:
+			return a.File < b.File
+		case a.Line != b.Line:
+			return a.Line < b.Line
+		default:
+			return a.Column < b.Column
+		}
+	})
+}
+
+// addFunction records the function in the result's functions section, along
+// with the generic origin of an instance, which may have no node of its own.
+func addFunction(result *CallGraphResult, fn *ssa.Function) FunctionInfo {
+	info := functionInfo(fn)
+	result.Functions[info.ID] = info
+	if origin := fn.Origin(); origin != nil {
+		if _, ok := result.Functions[info.Origin]; !ok {
+			result.Functions[info.Origin] = functionInfo(origin)
+		}
+	}
+	return info
+}
+
+// FunctionID returns the canonical identifier of a function, used as its
+// key in call_graph, functions and call_edges:
+//
+//	github.com/x/pkg.Func                  // package-level function
+//	(*github.com/x/pkg.Server).Start       // method on a pointer receiver
+//	(github.com/x/pkg.Point).String        // method on a value receiver
+//	github.com/x/pkg.Map[int string]       // generic instantiation
+//	(*github.com/x/pkg.List[int]).Push     // method of an instantiated type
+//	(*github.com/x/pkg.Server).Start$handler  // closure bound to a name
+//	(*github.com/x/pkg.Server).Start$5d41402a // other closure, by source hash
+//	(*github.com/x/pkg.Server).Start$bound    // bound method value
+//
+// Identifiers only depend on declarations, not on source positions, so they
+// can be joined across builds. Closures are identified within their
+// enclosing function by the name they are bound to, or else by a hash of
+// their source, as described by closureLabel; adding or moving another
+// closure leaves their identifiers unchanged.
+func FunctionID(fn *ssa.Function) string {
+	if fn.Parent() != nil {
+		return FunctionID(fn.Parent()) + "$" + closureLabel(fn)
+	}
+	// Wrappers promoting an unexported method from another package would
+	// otherwise share the name of a method declared on the receiver itself
+	if recv := fn.Signature.Recv(); recv != nil && fn.Parent() == nil {
+		if obj, ok := fn.Object().(*types.Func); ok && !obj.Exported() && obj.Pkg() != nil && obj.Pkg() != receiverPackage(recv.Type()) {
+			return fmt.Sprintf("(%s).%s.%s", types.TypeString(recv.Type(), nil), obj.Pkg().Path(), fn.Name())
+		}
+	}
+	return fn.String()
+}
+
+// receiverPackage returns the package declaring the receiver's named type.
+func receiverPackage(t types.Type) *types.Package {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Pkg()
+	}
+	return nil
+}
+
+// functionInfo extracts the identifier, name and signature of a function.
+func functionInfo(fn *ssa.Function) FunctionInfo {
+	info := FunctionInfo{
+		ID:      FunctionID(fn),
+		Name:    fn.Name(),
+		Package: functionPackagePath(fn),
+	}
+	if info.Package == "" {
+		info.Package = "builtin"
+	}
+	if fn.Signature == nil {
+		info.Signature = fn.Name() + "()"
+		return info
+	}
+	if recv := fn.Signature.Recv(); recv != nil {
+		info.Receiver = recv.Type().String()
+	}
+	if origin := fn.Origin(); origin != nil {
+		info.Origin = FunctionID(origin)
+		info.TypeArgs = typeArgs(fn)
+	} else {
+		info.TypeParams = typeParams(fn)
+	}
+
+	// Extract parameters and return types
+	var parameters, returns []string
+	sig := fn.Signature
+	for i := 0; i < sig.Params().Len(); i++ {
+		param := sig.Params().At(i)
+		if param.Name() != "" {
+			parameters = append(parameters, fmt.Sprintf("%s %s", param.Name(), param.Type().String()))
+		} else {
+			parameters = append(parameters, param.Type().String())
+		}
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		res := sig.Results().At(i)
+		if res.Name() != "" {
+			returns = append(returns, fmt.Sprintf("%s %s", res.Name(), res.Type().String()))
+		} else {
+			returns = append(returns, res.Type().String())
+		}
+	}
+
+	// Build full signature
+	paramStr := strings.Join(parameters, ", ")
+	returnStr := ""
+	if len(returns) == 1 {
+		returnStr = returns[0]
+	} else if len(returns) > 1 {
+		returnStr = "(" + strings.Join(returns, ", ") + ")"
+	}
+
+	// Generic functions declare their type parameters; those of methods
+	// belong to the receiver type
+	name := fn.Name()
+	if len(info.TypeParams) > 0 && fn.Signature.Recv() == nil {
+		name += "[" + strings.Join(info.TypeParams, ", ") + "]"
+	}
+	signature := fmt.Sprintf("%s(%s)", name, paramStr)
+	if returnStr != "" {
+		signature += " " + returnStr
+	}
+
+	info.Signature = signature
+	info.Parameters = parameters
+	info.Returns = returns
+	return info
+}
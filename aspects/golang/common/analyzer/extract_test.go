@@ -0,0 +1,48 @@
+package analyzer
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestExtractIsSorted(t *testing.T) {
+	pkgs := loadSources(t)
+	prog, _, _, err := BuildProgram(pkgs, AlgorithmCHA.builderMode(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cg, err := BuildCallGraph(prog, AlgorithmCHA, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := &Scope{All: true, internal: make(map[string]bool)}
+	first := NewResult(nil)
+	Extract(cg, first, scope)
+	if len(first.CallEdges) == 0 {
+		t.Fatal("no edges extracted")
+	}
+	sorted := sort.SliceIsSorted(first.CallEdges, func(i, j int) bool {
+		a, b := first.CallEdges[i], first.CallEdges[j]
+		if a.Caller != b.Caller {
+			return a.Caller < b.Caller
+		}
+		return a.Callee < b.Callee
+	})
+	if !sorted {
+		t.Error("call edges are not sorted by caller and callee")
+	}
+
+	// Map order differs between runs, the output must not
+	for i := 0; i < 3; i++ {
+		again := NewResult(nil)
+		Extract(cg, again, scope)
+		if !reflect.DeepEqual(again.CallEdges, first.CallEdges) {
+			t.Fatal("call edges differ between extractions of the same call graph")
+		}
+		if !reflect.DeepEqual(again.CallGraph, first.CallGraph) {
+			t.Fatal("call graph differs between extractions of the same call graph")
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// typeParams formats the type parameters of a generic function, or of the
+// receiver type of a generic method, e.g. ["K comparable", "V any"].
+func typeParams(fn *ssa.Function) []string {
+	var params []string
+	tparams := fn.TypeParams()
+	for i := 0; i < tparams.Len(); i++ {
+		tparam := tparams.At(i)
+		params = append(params, fmt.Sprintf("%s %s", tparam.Obj().Name(), types.TypeString(tparam.Constraint(), nil)))
+	}
+	return params
+}
+
+// typeArgs formats the type arguments of a generic instantiation.
+func typeArgs(fn *ssa.Function) []string {
+	var args []string
+	for _, arg := range fn.TypeArgs() {
+		args = append(args, types.TypeString(arg, nil))
+	}
+	return args
+}
+
+// instanceKey identifies an instantiation: the generic function, or the
+// generic type of a method, and its type arguments.
+type instanceKey struct {
+	obj  types.Object
+	args string
+}
+
+// InstantiationSites records where generic functions and types are
+// instantiated in the source of the loaded packages.
+type InstantiationSites map[instanceKey][]string
+
+// NewInstantiationSites collects the instantiations type-checking recorded
+// for the packages loaded from source, as file:line:column positions.
+func NewInstantiationSites(pkgs []*packages.Package) InstantiationSites {
+	sites := make(InstantiationSites)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.TypesInfo == nil || pkg.Fset == nil {
+			return
+		}
+		for ident, instance := range pkg.TypesInfo.Instances {
+			obj := genericObject(pkg.TypesInfo.Uses[ident])
+			if obj == nil {
+				continue
+			}
+			var args []types.Type
+			for i := 0; i < instance.TypeArgs.Len(); i++ {
+				args = append(args, instance.TypeArgs.At(i))
+			}
+			key := instanceKey{obj, typeListString(args)}
+			pos := pkg.Fset.Position(ident.Pos())
+			sites[key] = append(sites[key], fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column))
+		}
+	})
+	for key := range sites {
+		sort.Strings(sites[key])
+	}
+	return sites
+}
+
+// Lookup returns the instantiation sites of a generic instance function.
+// Methods are instantiated with their receiver type, so the sites are the
+// instantiations of that type.
+func (s InstantiationSites) Lookup(fn *ssa.Function) []string {
+	origin := fn.Origin()
+	if origin == nil {
+		return nil
+	}
+	obj := genericObject(origin.Object())
+	if recv := origin.Signature.Recv(); recv != nil {
+		if named := namedReceiver(recv.Type()); named != nil {
+			obj = named.Obj()
+		}
+	}
+	if obj == nil {
+		return nil
+	}
+	return s[instanceKey{obj, typeListString(fn.TypeArgs())}]
+}
+
+// genericObject returns the generic declaration of a function or type name.
+func genericObject(obj types.Object) types.Object {
+	switch obj := obj.(type) {
+	case *types.Func:
+		return obj.Origin()
+	case *types.TypeName:
+		if named, ok := obj.Type().(*types.Named); ok {
+			return named.Origin().Obj()
+		}
+	}
+	return nil
+}
+
+// namedReceiver returns the generic named type of a method receiver.
+func namedReceiver(t types.Type) *types.Named {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Origin()
+	}
+	return nil
+}
+
+// typeListString formats a list of types as a map key.
+func typeListString(list []types.Type) string {
+	var args []string
+	for _, t := range list {
+		args = append(args, types.TypeString(t, nil))
+	}
+	return strings.Join(args, ", ")
+}
+
+// AnnotateInstances records the instantiation sites of the generic instances
+// among the functions of the result.
+func AnnotateInstances(cg *callgraph.Graph, result *CallGraphResult, sites InstantiationSites) {
+	for fn := range cg.Nodes {
+		if fn == nil || len(fn.TypeArgs()) == 0 {
+			continue
+		}
+		id := FunctionID(fn)
+		if info, ok := result.Functions[id]; ok {
+			info.InstantiatedAt = sites.Lookup(fn)
+			result.Functions[id] = info
+		}
+	}
+}
+
+// CollapseGenerics returns a copy of the result in which every generic
+// instance is replaced by its generic origin. Calls made at the same site by
+// several instances of one function count once, so the collapsed edges are
+// the calls as written in the source. As in Extract, call_graph lists one
+// callee per remaining edge.
+func CollapseGenerics(result *CallGraphResult) *CallGraphResult {
+	collapsed := *result
+	collapsed.CallGraph = make(map[string][]string)
+	collapsed.Functions = make(map[string]FunctionInfo)
+	collapsed.CallEdges = []NormalizedEdge{}
+
+	origin := func(id string) FunctionInfo {
+		info, ok := result.Functions[id]
+		if !ok {
+			return FunctionInfo{ID: id}
+		}
+		if info.Origin == "" {
+			return info
+		}
+		if originInfo, ok := result.Functions[info.Origin]; ok {
+			return originInfo
+		}
+		return FunctionInfo{ID: info.Origin, Name: info.Name, Package: info.Package}
+	}
+
+	type siteKey struct {
+		caller, callee, file string
+		line, column         int
+		mode                 string
+	}
+	seen := make(map[siteKey]bool)
+	for _, edge := range result.CallEdges {
+		caller, callee := origin(edge.Caller), origin(edge.Callee)
+		edge.Caller, edge.Callee = caller.ID, callee.ID
+		key := siteKey{edge.Caller, edge.Callee, edge.File, edge.Line, edge.Column, edge.Mode}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		collapsed.CallEdges = append(collapsed.CallEdges, edge)
+		collapsed.Functions[caller.ID] = caller
+		collapsed.Functions[callee.ID] = callee
+		collapsed.CallGraph[edge.Caller] = append(collapsed.CallGraph[edge.Caller], edge.Callee)
+	}
+	for id, info := range result.Functions {
+		if _, ok := collapsed.Functions[id]; !ok && info.Origin == "" {
+			collapsed.Functions[id] = info
+		}
+	}
+
+	collapsed.TotalFuncs = len(collapsed.CallGraph)
+	collapsed.TotalEdges = len(collapsed.CallEdges)
+	return &collapsed
+}
@@ -0,0 +1,293 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// LoaderMode selects how packages are loaded for SSA construction.
+type LoaderMode string
+
+const (
+	// LoaderExport builds packages from the packages JSON written by the
+	// aspect, loading syntax only for workspace sources.
+	LoaderExport LoaderMode = "export"
+	// LoaderWorkspace loads the workspace packages named in the packages
+	// JSON from the workspace root.
+	LoaderWorkspace LoaderMode = "workspace"
+	// LoaderExportData type-checks the root packages of the packages JSON
+	// from source and reads every dependency from its rules_go export
+	// file, without running the go command. Standard library packages
+	// without export data are type-checked from the sources of the Go SDK.
+	// Dependencies have no function bodies, so the call graph stops at the
+	// functions they export.
+	LoaderExportData LoaderMode = "exportdata"
+	// LoaderCwd loads the current directory as a Go module, falling back to
+	// the Go files found beneath it.
+	LoaderCwd LoaderMode = "cwd"
+)
+
+// ParseLoaderMode validates a --loader flag value.
+func ParseLoaderMode(s string) (LoaderMode, error) {
+	switch mode := LoaderMode(s); mode {
+	case LoaderExport, LoaderExportData, LoaderWorkspace, LoaderCwd:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown loader %q (want export, exportdata, workspace or cwd)", s)
+}
+
+const loadMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedImports | packages.NeedDeps | packages.NeedExportFile |
+	packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo |
+	packages.NeedTypesSizes | packages.NeedModule
+
+// Load loads the packages described by the response using the configured
+// loader strategy.
+func Load(cfg *Config, response *PackagesResponse) ([]*packages.Package, error) {
+	if err := setupGoEnvironment(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Loader != LoaderExportData {
+		if err := checkGoCommand(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Tests && (cfg.Loader == LoaderExport || cfg.Loader == LoaderExportData) {
+		// These loaders take the package list from the JSON as is
+		cfg.diagnose(SeverityWarning, DiagnosticFallback, "", "--tests does not load test packages with the %s loader; only the test packages listed in the packages JSON are analyzed", cfg.Loader)
+	}
+	switch cfg.Loader {
+	case LoaderExport:
+		return loadExport(cfg, response)
+	case LoaderExportData:
+		return loadExportData(cfg, response)
+	case LoaderWorkspace:
+		pkgs, err := loadWorkspace(cfg, response)
+		return withoutTestMains(pkgs), err
+	case LoaderCwd:
+		pkgs, err := loadCwd(cfg)
+		return withoutTestMains(pkgs), err
+	}
+	return nil, fmt.Errorf("unknown loader %q", cfg.Loader)
+}
+
+// loadExport converts the JSON packages to go/packages form, loading syntax
+// for workspace sources and export data for the stdlib. Stdlib packages that
+// a source package already loaded as a dependency are shared with it, so both
+// see the same types.
+func loadExport(cfg *Config, response *PackagesResponse) ([]*packages.Package, error) {
+	env := buildGoEnvironment(cfg, ".")
+
+	std, err := StdlibPackages(response, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine standard library packages: %v", err)
+	}
+
+	pkgs := make([]*packages.Package, len(response.Packages))
+	loaded := make(map[string]*packages.Package)
+	for i, jsonPkg := range response.Packages {
+		pkg := &packages.Package{
+			ID:              jsonPkg.ID,
+			Name:            jsonPkg.Name,
+			PkgPath:         jsonPkg.PkgPath,
+			GoFiles:         jsonPkg.GoFiles,
+			CompiledGoFiles: jsonPkg.CompiledGoFiles,
+			ExportFile:      jsonPkg.ExportFile,
+			Imports:         make(map[string]*packages.Package),
+		}
+
+		// Only load syntax for source packages (not stdlib or external deps)
+		if IsSourcePackage(pkg.ID) && len(pkg.GoFiles) > 0 {
+			if err := loadPackageSyntax(cfg, pkg); err != nil {
+				cfg.diagnose(SeverityError, DiagnosticLoadError, pkg.ID, "failed to load syntax for %s: %v", pkg.PkgPath, err)
+			}
+			packages.Visit([]*packages.Package{pkg}, nil, func(dep *packages.Package) {
+				if dep.Types != nil {
+					loaded[dep.PkgPath] = dep
+				}
+			})
+		}
+
+		pkgs[i] = pkg
+	}
+
+	// Load the remaining stdlib packages from export data
+	var stdPaths []string
+	for _, pkg := range pkgs {
+		if pkg.Types == nil && std[pkg.PkgPath] && loaded[pkg.PkgPath] == nil {
+			stdPaths = append(stdPaths, pkg.PkgPath)
+		}
+	}
+	exported, err := loadStdlibExportData(stdPaths, env)
+	if err != nil {
+		cfg.diagnose(SeverityWarning, DiagnosticLoadError, "", "failed to load stdlib export data: %v", err)
+	}
+	for i, pkg := range pkgs {
+		if pkg.Types != nil || !std[pkg.PkgPath] {
+			continue
+		}
+		if dep := loaded[pkg.PkgPath]; dep != nil {
+			pkgs[i] = dep
+		} else if dep := exported[pkg.PkgPath]; dep != nil {
+			pkgs[i] = dep
+		}
+	}
+	cfg.logf("📚 %d stdlib packages known, %d loaded from export data\n", len(std), len(exported))
+	return pkgs, nil
+}
+
+// loadPackageSyntax loads the syntax and types of a source package from the
+// directory of its first file. Outside a module, as in the Bazel sandbox,
+// the go command runs in GOPATH mode, so only standard library imports
+// resolve. In a module, the go command's default -mod mode applies, which
+// never rewrites go.mod or go.sum.
+func loadPackageSyntax(cfg *Config, pkg *packages.Package) error {
+	if len(pkg.GoFiles) == 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(pkg.GoFiles[0])
+	loadCfg := &packages.Config{
+		Mode:       loadMode,
+		Dir:        dir,
+		Env:        buildGoEnvironment(cfg, dir),
+		BuildFlags: buildFlags(cfg),
+		Tests:      false,
+	}
+
+	// Load syntax for the source file
+	loadedPkgs, err := packages.Load(loadCfg, "file="+pkg.GoFiles[0])
+	if err == nil && len(loadedPkgs) > 0 {
+		loadedPkg := loadedPkgs[0]
+		pkg.Syntax = loadedPkg.Syntax
+		pkg.Fset = loadedPkg.Fset
+		pkg.Types = loadedPkg.Types
+		pkg.TypesInfo = loadedPkg.TypesInfo
+		pkg.TypesSizes = loadedPkg.TypesSizes
+		pkg.Module = loadedPkg.Module
+		pkg.Errors = loadedPkg.Errors
+		// Keep the dependencies loaded with the package, whose types
+		// are the ones the package was checked against
+		pkg.Imports = loadedPkg.Imports
+	}
+
+	return err
+}
+
+// loadWorkspace loads the workspace packages named in the response from the
+// workspace root with the go command, in-process through go/packages.
+func loadWorkspace(cfg *Config, response *PackagesResponse) ([]*packages.Package, error) {
+	if cfg.WorkspaceRoot == "" {
+		return nil, fmt.Errorf("the workspace loader requires a workspace root (--workspace)")
+	}
+	workspaceRoot, err := filepath.Abs(cfg.WorkspaceRoot)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace root %s: %v", cfg.WorkspaceRoot, err)
+	}
+	if _, err := os.Stat(filepath.Join(workspaceRoot, "go.mod")); err != nil {
+		return nil, fmt.Errorf("workspace root %s has no go.mod", workspaceRoot)
+	}
+	cfg.logf("🏠 Workspace root: %s\n", workspaceRoot)
+
+	// Convert Bazel packages to patterns relative to the workspace root
+	var patterns []string
+	for _, pkg := range response.SourcePackages() {
+		dir := labelPackageDir(pkg.ID)
+		if dir == "" {
+			dir = pkg.PkgPath
+		}
+		patterns = append(patterns, "./"+dir)
+		cfg.logf("📦 Will analyze package: ./%s (from %s)\n", dir, pkg.ID)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no workspace packages in the packages JSON")
+	}
+	if cfg.Tests {
+		// The tests of every workspace package may reach the target's
+		// functions, not only the tests of the target itself
+		patterns = append(patterns, "./...")
+	}
+
+	env, flags, cleanup, err := workspaceEnvironment(cfg, workspaceRoot)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	loadCfg := &packages.Config{
+		Mode:       loadMode,
+		Dir:        workspaceRoot,
+		Env:        env,
+		BuildFlags: flags,
+		Tests:      cfg.Tests,
+	}
+
+	cfg.logf("🔄 Loading %d packages %v with %v\n", len(patterns), patterns, flags)
+	return packages.Load(loadCfg, patterns...)
+}
+
+// labelPackageDir returns the package directory of a Bazel label such as
+// "@@//src/main:main", or "" when the ID is not a label.
+func labelPackageDir(id string) string {
+	i := strings.Index(id, "//")
+	if i < 0 {
+		return ""
+	}
+	dir := id[i+2:]
+	if j := strings.Index(dir, ":"); j >= 0 {
+		dir = dir[:j]
+	}
+	return dir
+}
+
+// loadCwd loads the current directory as a Go module. In the Bazel sandbox
+// the source directories may not form a module, so it falls back to loading
+// the Go files found beneath the current directory.
+func loadCwd(cfg *Config) ([]*packages.Package, error) {
+	loadCfg := &packages.Config{
+		Mode:       loadMode,
+		Tests:      cfg.Tests,
+		Env:        buildGoEnvironment(cfg, "."),
+		BuildFlags: buildFlags(cfg),
+	}
+
+	cfg.logf("🔄 Loading current directory as Go module\n")
+	pkgs, err := packages.Load(loadCfg, ".")
+	if err == nil && len(pkgs) > 0 {
+		return pkgs, nil
+	}
+	cfg.logf("⚠️ Current directory load failed: %v\n", err)
+
+	goFiles, findErr := findGoFiles(".")
+	if findErr != nil {
+		return nil, fmt.Errorf("failed to find Go files: %v", findErr)
+	}
+	if len(goFiles) == 0 {
+		return nil, err
+	}
+
+	cfg.diagnose(SeverityWarning, DiagnosticFallback, "", "the current directory is not a loadable Go module, loading its %d Go files instead", len(goFiles))
+	filePatterns := make([]string, len(goFiles))
+	for i, f := range goFiles {
+		filePatterns[i] = "file=" + f
+	}
+	return packages.Load(loadCfg, filePatterns...)
+}
+
+// filterValidPackages returns the packages SSA can be built for: those with
+// types and without type errors in them or their dependencies. The SSA
+// builder assumes well-typed syntax and panics on anything else, so the
+// others are left out and reported as skipped.
+func filterValidPackages(pkgs []*packages.Package) []*packages.Package {
+	var validPackages []*packages.Package
+	for _, pkg := range pkgs {
+		if pkg.Types != nil && !pkg.IllTyped {
+			validPackages = append(validPackages, pkg)
+		}
+	}
+	return validPackages
+}
@@ -0,0 +1,168 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// OSVEntry is the subset of an OSV advisory used for Go reachability, as
+// published by the Go vulnerability database.
+type OSVEntry struct {
+	ID       string        `json:"id"`
+	Aliases  []string      `json:"aliases"`
+	Summary  string        `json:"summary"`
+	Affected []OSVAffected `json:"affected"`
+}
+
+// OSVAffected lists the affected versions and symbols of one module.
+type OSVAffected struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"`
+		Events []struct {
+			Introduced   string `json:"introduced,omitempty"`
+			Fixed        string `json:"fixed,omitempty"`
+			LastAffected string `json:"last_affected,omitempty"`
+		} `json:"events"`
+	} `json:"ranges"`
+	EcosystemSpecific struct {
+		Imports []OSVImport `json:"imports"`
+	} `json:"ecosystem_specific"`
+}
+
+// OSVImport names a vulnerable package and, when known, its vulnerable
+// symbols as "Func" or "Type.Method".
+type OSVImport struct {
+	Path    string   `json:"path"`
+	Symbols []string `json:"symbols"`
+}
+
+// LoadOSVDatabase reads advisories from an offline OSV dump. The path may be
+// a single JSON file holding one entry or an array of entries, or a
+// directory whose JSON files are read recursively. Files that are not OSV
+// entries, such as the vulndb index files, are skipped.
+func LoadOSVDatabase(path string) ([]OSVEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OSV database: %v", err)
+	}
+	if !info.IsDir() {
+		return readOSVFile(path)
+	}
+
+	var entries []OSVEntry
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(file, ".json") {
+			return nil
+		}
+		fileEntries, err := readOSVFile(file)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, fileEntries...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OSV database: %v", err)
+	}
+	return entries, nil
+}
+
+func readOSVFile(path string) ([]OSVEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []OSVEntry
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+	} else {
+		var entry OSVEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	// Skip index files and other non-advisory JSON
+	var advisories []OSVEntry
+	for _, entry := range entries {
+		if entry.ID != "" && len(entry.Affected) > 0 {
+			advisories = append(advisories, entry)
+		}
+	}
+	return advisories, nil
+}
+
+// AffectsVersion reports whether the module version falls in an affected
+// SEMVER range. Versions that are not valid semver, such as the "external"
+// placeholder of unresolved repositories, cannot be compared and are not
+// affected; VulnerabilityReachability reports them as version_unknown.
+func (a OSVAffected) AffectsVersion(version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" {
+			continue
+		}
+		affected := false
+		for _, event := range r.Events {
+			switch {
+			case event.Introduced != "":
+				if event.Introduced == "0" || semver.Compare(version, osvVersion(event.Introduced)) >= 0 {
+					affected = true
+				}
+			case event.Fixed != "":
+				if semver.Compare(version, osvVersion(event.Fixed)) >= 0 {
+					affected = false
+				}
+			case event.LastAffected != "":
+				if semver.Compare(version, osvVersion(event.LastAffected)) > 0 {
+					affected = false
+				}
+			}
+		}
+		if affected {
+			return true
+		}
+	}
+	return false
+}
+
+// osvVersion converts an OSV Go version, which has no "v" prefix, to semver.
+func osvVersion(v string) string {
+	return "v" + strings.TrimPrefix(v, "v")
+}
+
+// inModule reports whether the package path belongs to the module, treating
+// major version suffixes such as /v8 as separate modules.
+func inModule(pkgPath, module string) bool {
+	if pkgPath == module {
+		return true
+	}
+	if !strings.HasPrefix(pkgPath, module+"/") {
+		return false
+	}
+	first := strings.SplitN(strings.TrimPrefix(pkgPath, module+"/"), "/", 2)[0]
+	if len(first) > 1 && first[0] == 'v' && strings.Trim(first[1:], "0123456789") == "" {
+		return false
+	}
+	return true
+}
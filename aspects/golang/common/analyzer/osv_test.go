@@ -0,0 +1,174 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// testAffected returns the affected module of an OSV entry written as JSON.
+func testAffected(t *testing.T, data string) OSVAffected {
+	t.Helper()
+	var affected OSVAffected
+	if err := json.Unmarshal([]byte(data), &affected); err != nil {
+		t.Fatal(err)
+	}
+	return affected
+}
+
+func TestAffectsVersion(t *testing.T) {
+	fixed := `{"package": {"name": "example.com/dep"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.2.0"}, {"fixed": "1.4.1"}]}]}`
+	lastAffected := `{"package": {"name": "example.com/dep"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"last_affected": "0.9.0"}]}]}`
+	twoRanges := `{"package": {"name": "example.com/dep"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.0.0"}, {"introduced": "2.0.0"}, {"fixed": "2.1.0"}]}]}`
+	otherType := `{"package": {"name": "example.com/dep"}, "ranges": [{"type": "GIT", "events": [{"introduced": "0"}]}]}`
+
+	tests := []struct {
+		affected string
+		version  string
+		want     bool
+	}{
+		{fixed, "v1.1.9", false},
+		{fixed, "v1.2.0", true},
+		{fixed, "v1.4.0", true},
+		{fixed, "v1.4.1", false},
+		{fixed, "v1.4.1-pre", true},
+		{lastAffected, "v0.1.0", true},
+		{lastAffected, "v0.9.0", true},
+		{lastAffected, "v0.9.1", false},
+		{twoRanges, "v0.5.0", true},
+		{twoRanges, "v1.5.0", false},
+		{twoRanges, "v2.0.5", true},
+		{twoRanges, "v2.1.0", false},
+		{otherType, "v1.0.0", false},
+		// Versions that cannot be compared are left to VulnerabilityReachability
+		{fixed, "external", false},
+		{fixed, "", false},
+	}
+	for _, tt := range tests {
+		if got := testAffected(t, tt.affected).AffectsVersion(tt.version); got != tt.want {
+			t.Errorf("AffectsVersion(%q) of %s = %v, want %v", tt.version, tt.affected, got, tt.want)
+		}
+	}
+}
+
+func TestInModule(t *testing.T) {
+	tests := []struct {
+		pkgPath, module string
+		want            bool
+	}{
+		{"example.com/dep", "example.com/dep", true},
+		{"example.com/dep/sub", "example.com/dep", true},
+		{"example.com/dependency", "example.com/dep", false},
+		{"example.com/dep/v2", "example.com/dep", false},
+		{"example.com/dep/v2/sub", "example.com/dep", false},
+		{"example.com/dep/v2/sub", "example.com/dep/v2", true},
+		{"example.com/dep/vendor", "example.com/dep", true},
+	}
+	for _, tt := range tests {
+		if got := inModule(tt.pkgPath, tt.module); got != tt.want {
+			t.Errorf("inModule(%q, %q) = %v, want %v", tt.pkgPath, tt.module, got, tt.want)
+		}
+	}
+}
+
+func TestVulnerabilityReachability(t *testing.T) {
+	prog, pkgs, cg := testProgram(t, map[string]string{
+		"app/app.go": `package app
+
+import (
+	"example.com/dep/a"
+	"example.com/dep/b"
+)
+
+func Run() { a.Parse(); a.Server{}.Serve() }
+
+func helper() { b.Do() }
+`,
+		"dep/a/a.go": `package a
+
+type Server struct{}
+
+func (Server) Serve() {}
+func (Server) Close() {}
+
+func Parse() {}
+func Format() {}
+`,
+		"dep/b/b.go": `package b
+
+func Do() {}
+`,
+	})
+	roots := ReachabilityRoots(pkgs["example.com/app"])
+	deps := []Dependency{{OriginalLabel: "@dep//:dep", Name: "dep", Version: "v1.0.0", ImportPath: "example.com/dep"}}
+
+	entry := func(id string, imports ...OSVImport) OSVEntry {
+		affected := testAffected(t, `{"package": {"name": "example.com/dep"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.1.0"}]}]}`)
+		affected.EcosystemSpecific.Imports = imports
+		return OSVEntry{ID: id, Affected: []OSVAffected{affected}}
+	}
+	finding := func(id, pkg string, symbols []string, status string) VulnFinding {
+		return VulnFinding{ID: id, Module: "example.com/dep", Version: "v1.0.0", Label: "@dep//:dep", Package: pkg, Symbols: symbols, Status: status}
+	}
+
+	reachable := finding("GO-1", "example.com/dep/a", []string{"Format", "Server.Serve"}, VulnReachable)
+	reachable.ReachableSymbols = []string{"Server.Serve"}
+	reachable.CallChain = []string{"example.com/app.Run", "(example.com/dep/a.Server).Serve"}
+
+	unknown := finding("GO-6", "example.com/dep/a", []string{"Parse"}, VulnReachable)
+	unknown.Version, unknown.VersionUnknown = "external", true
+	unknown.ReachableSymbols = []string{"Parse"}
+	unknown.CallChain = []string{"example.com/app.Run", "example.com/dep/a.Parse"}
+
+	tests := []struct {
+		name  string
+		entry OSVEntry
+		// deps replace the dependency at v1.0.0 when set
+		deps []Dependency
+		want []VulnFinding
+	}{
+		{
+			name:  "reachable method",
+			entry: entry("GO-1", OSVImport{Path: "example.com/dep/a", Symbols: []string{"Format", "Server.Serve"}}),
+			want:  []VulnFinding{reachable},
+		},
+		{
+			name:  "imported but not called",
+			entry: entry("GO-2", OSVImport{Path: "example.com/dep/a", Symbols: []string{"Format", "Server.Close"}}),
+			want:  []VulnFinding{finding("GO-2", "example.com/dep/a", []string{"Format", "Server.Close"}, VulnImported)},
+		},
+		{
+			// b.Do is only called from an unexported function
+			name:  "imported from unreachable code",
+			entry: entry("GO-3", OSVImport{Path: "example.com/dep/b", Symbols: []string{"Do"}}),
+			want:  []VulnFinding{finding("GO-3", "example.com/dep/b", []string{"Do"}, VulnImported)},
+		},
+		{
+			name:  "not imported",
+			entry: entry("GO-4", OSVImport{Path: "example.com/dep/c"}),
+			want:  []VulnFinding{finding("GO-4", "example.com/dep/c", nil, VulnUnreachable)},
+		},
+		{
+			name:  "not an affected version",
+			entry: OSVEntry{ID: "GO-5", Affected: []OSVAffected{testAffected(t, `{"package": {"name": "example.com/dep"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "2.0.0"}]}]}`)}},
+		},
+		{
+			name:  "unknown version",
+			entry: entry("GO-6", OSVImport{Path: "example.com/dep/a", Symbols: []string{"Parse"}}),
+			deps:  []Dependency{{OriginalLabel: "@dep//:dep", Name: "dep", Version: "external", ImportPath: "example.com/dep"}},
+			want:  []VulnFinding{unknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := deps
+			if tt.deps != nil {
+				deps = tt.deps
+			}
+			got := VulnerabilityReachability(prog, cg, roots, deps, []OSVEntry{tt.entry})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VulnerabilityReachability =\n  %+v\nwant\n  %+v", got, tt.want)
+			}
+		})
+	}
+}
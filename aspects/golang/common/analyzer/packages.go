@@ -0,0 +1,136 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+)
+
+// PackagesResponse is a gopackagesdriver DriverResponse, as written by the
+// aspects or returned by the driver, consumed by the analyzer.
+type PackagesResponse struct {
+	NotHandled bool
+	Compiler   string
+	Arch       string
+	Roots      []string
+	Packages   []*PackageJSON
+	GoVersion  int `json:",omitempty"`
+	// SchemaVersion is the version of the packages schema; drivers do not
+	// set it, WritePackages does
+	SchemaVersion int `json:"schema_version,omitempty"`
+}
+
+// PackageJSON describes a single package entry of the packages JSON.
+type PackageJSON struct {
+	ID              string            `json:"ID"`
+	Name            string            `json:"Name"`
+	PkgPath         string            `json:"PkgPath"`
+	GoFiles         []string          `json:"GoFiles"`
+	CompiledGoFiles []string          `json:"CompiledGoFiles"`
+	Imports         map[string]string `json:"Imports"`
+	ExportFile      string            `json:"ExportFile"`
+}
+
+// ReadPackagesFile reads and parses the packages JSON generated by the aspect
+// or the driver. The path "-" reads it from stdin.
+func ReadPackagesFile(path string) (*PackagesResponse, error) {
+	if path == "-" {
+		return ReadPackages(os.Stdin)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read packages file: %v", err)
+	}
+	defer file.Close()
+	return ReadPackages(file)
+}
+
+// ReadPackages parses a packages JSON document.
+func ReadPackages(r io.Reader) (*PackagesResponse, error) {
+	var response PackagesResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse packages JSON: %v", err)
+	}
+	return &response, nil
+}
+
+// WritePackages stamps the response with the current schema version,
+// validates it against the packages schema and writes it as indented JSON
+// to w.
+func WritePackages(w io.Writer, response *PackagesResponse) error {
+	response.SchemaVersion = schema.Version
+	data, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal packages JSON: %v", err)
+	}
+	if err := schema.Validate(schema.Packages, data); err != nil {
+		return fmt.Errorf("invalid packages JSON: %v", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// IsSourcePackage reports whether a package ID refers to a package of the
+// main workspace rather than the stdlib or an external repository.
+func IsSourcePackage(id string) bool {
+	if id == "" || IsStdlibPackage(id) {
+		return false
+	}
+	return id[0] != '@' || strings.HasPrefix(id, "@//") || strings.HasPrefix(id, "@@//")
+}
+
+// SourcePackages returns the workspace packages of the response.
+func (r *PackagesResponse) SourcePackages() []*PackageJSON {
+	var pkgs []*PackageJSON
+	for _, pkg := range r.Packages {
+		if IsSourcePackage(pkg.ID) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs
+}
+
+// ExternalPackages returns the packages of external repositories: those
+// that are neither workspace nor stdlib packages. Their import paths come
+// from the driver's metadata.
+func (r *PackagesResponse) ExternalPackages() []*PackageJSON {
+	var pkgs []*PackageJSON
+	for _, pkg := range r.Packages {
+		if pkg.ID != "" && !IsSourcePackage(pkg.ID) && !IsStdlibPackage(pkg.ID) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs
+}
+
+// RootPackage returns the package the analysis is reported for: the first
+// workspace package among the roots, else the first workspace package with Go
+// files, or the first workspace package at all when the aspect left file
+// discovery to the loader.
+func (r *PackagesResponse) RootPackage() *PackageJSON {
+	for _, id := range r.Roots {
+		if !IsSourcePackage(id) {
+			continue
+		}
+		for _, pkg := range r.Packages {
+			if pkg.ID == id {
+				return pkg
+			}
+		}
+	}
+
+	sources := r.SourcePackages()
+	for _, pkg := range sources {
+		if len(pkg.GoFiles) > 0 {
+			return pkg
+		}
+	}
+	if len(sources) > 0 {
+		return sources[0]
+	}
+	return nil
+}
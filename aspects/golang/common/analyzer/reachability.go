@@ -0,0 +1,232 @@
+package analyzer
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// ModuleReachability reports which functions of an external dependency are
+// reachable from the entrypoints of the analyzed target.
+type ModuleReachability struct {
+	Label      string   `json:"label"`
+	Name       string   `json:"name"`
+	Version    string   `json:"version"`
+	ImportPath string   `json:"import_path"`
+	Reachable  bool     `json:"reachable"`
+	Functions  []string `json:"functions"`
+	// Witness is one shortest call path from an entrypoint to a function
+	// of the dependency, preferring functions other than its initializers.
+	Witness []string `json:"witness,omitempty"`
+}
+
+// ReachabilityRoots returns the entrypoints reachability starts from: the
+// main and init functions of the package plus its exported API, that is
+// exported functions and exported methods of exported types. Methods are
+// the declared functions rather than the wrappers of the method sets of
+// pointer types, which the call graph no longer holds.
+func ReachabilityRoots(pkg *ssa.Package) []*ssa.Function {
+	roots := entrypoints(pkg)
+	if pkg == nil {
+		return roots
+	}
+
+	var names []string
+	for name := range pkg.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	seen := make(map[*ssa.Function]bool)
+	for _, name := range names {
+		if !token.IsExported(name) {
+			continue
+		}
+		switch member := pkg.Members[name].(type) {
+		case *ssa.Function:
+			roots = append(roots, member)
+		case *ssa.Type:
+			named, ok := member.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 {
+				continue
+			}
+			mset := pkg.Prog.MethodSets.MethodSet(types.NewPointer(named))
+			for i := 0; i < mset.Len(); i++ {
+				sel := mset.At(i)
+				if !sel.Obj().Exported() {
+					continue
+				}
+				if fn := pkg.Prog.MethodValue(sel); fn != nil {
+					if fn = declaredFunction(fn); !seen[fn] {
+						seen[fn] = true
+						roots = append(roots, fn)
+					}
+				}
+			}
+		}
+	}
+	return roots
+}
+
+// Reachability walks the call graph breadth-first from the roots and reports,
+// for every external dependency, the reachable functions of its packages and
+// one shortest witness path. Internal dependencies are skipped.
+func Reachability(cg *callgraph.Graph, roots []*ssa.Function, deps []Dependency) []ModuleReachability {
+	var modules []*ModuleReachability
+	for _, dep := range deps {
+		if dep.Internal || dep.Path() == "" {
+			continue
+		}
+		modules = append(modules, &ModuleReachability{
+			Label:      dep.OriginalLabel,
+			Name:       dep.Name,
+			Version:    dep.Version,
+			ImportPath: dep.Path(),
+			Functions:  []string{},
+		})
+	}
+
+	// Match functions against the most specific import path first
+	byPath := make([]*ModuleReachability, len(modules))
+	copy(byPath, modules)
+	sort.SliceStable(byPath, func(i, j int) bool {
+		return len(byPath[i].ImportPath) > len(byPath[j].ImportPath)
+	})
+	matchModule := func(fn *ssa.Function) *ModuleReachability {
+		pkgPath := functionPackagePath(fn)
+		for _, m := range byPath {
+			if pkgPath == m.ImportPath || strings.HasPrefix(pkgPath, m.ImportPath+"/") {
+				return m
+			}
+		}
+		return nil
+	}
+
+	// Package initializers are trivially reachable through the init chain,
+	// so a path to any other function of the dependency is preferred
+	initWitness := make(map[*ModuleReachability]bool)
+
+	walk := walkCallGraph(cg, roots)
+	for _, fn := range walk.order {
+		if m := matchModule(fn); m != nil {
+			m.Reachable = true
+			m.Functions = append(m.Functions, FunctionID(fn))
+			if m.Witness == nil || (initWitness[m] && !isPackageInit(fn)) {
+				m.Witness = walk.path(fn)
+				initWitness[m] = isPackageInit(fn)
+			}
+		}
+	}
+
+	result := make([]ModuleReachability, len(modules))
+	for i, m := range modules {
+		sort.Strings(m.Functions)
+		result[i] = *m
+	}
+	return result
+}
+
+// callGraphWalk is the result of a breadth-first walk of a call graph.
+type callGraphWalk struct {
+	// order lists the reachable functions in BFS order, so the first
+	// function found with some property is the closest one to a root.
+	order  []*ssa.Function
+	index  map[*ssa.Function]int
+	parent map[*ssa.Function]*ssa.Function
+}
+
+// walkCallGraph walks the call graph breadth-first from the roots.
+func walkCallGraph(cg *callgraph.Graph, roots []*ssa.Function) *callGraphWalk {
+	return walkCallGraphWithin(cg, roots, nil)
+}
+
+// walkCallGraphWithin walks the call graph breadth-first from the roots,
+// following only the call edges for which follow returns true. A nil follow
+// follows every call.
+func walkCallGraphWithin(cg *callgraph.Graph, roots []*ssa.Function, follow func(*callgraph.Edge) bool) *callGraphWalk {
+	walk := &callGraphWalk{
+		index:  make(map[*ssa.Function]int),
+		parent: make(map[*ssa.Function]*ssa.Function),
+	}
+	for _, root := range roots {
+		if _, ok := walk.index[root]; root != nil && !ok {
+			walk.index[root] = len(walk.order)
+			walk.order = append(walk.order, root)
+		}
+	}
+
+	for i := 0; i < len(walk.order); i++ {
+		fn := walk.order[i]
+		node := cg.Nodes[fn]
+		if node == nil {
+			continue
+		}
+		for _, callee := range sortedCallees(node, follow) {
+			if _, ok := walk.index[callee]; !ok {
+				walk.index[callee] = len(walk.order)
+				walk.parent[callee] = fn
+				walk.order = append(walk.order, callee)
+			}
+		}
+	}
+	return walk
+}
+
+// path follows BFS parents back from fn to a root and returns the call path
+// from that root to fn.
+func (w *callGraphWalk) path(fn *ssa.Function) []string {
+	var path []string
+	for cur := fn; cur != nil; cur = w.parent[cur] {
+		path = append(path, FunctionID(cur))
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// sortedCallees returns the distinct callees of a node through the edges
+// follow accepts, or all edges when it is nil, ordered by name, so witness
+// paths are stable across runs.
+func sortedCallees(node *callgraph.Node, follow func(*callgraph.Edge) bool) []*ssa.Function {
+	seen := make(map[*ssa.Function]bool)
+	var callees []*ssa.Function
+	for _, edge := range node.Out {
+		if edge == nil || edge.Callee == nil || edge.Callee.Func == nil || seen[edge.Callee.Func] {
+			continue
+		}
+		if follow != nil && !follow(edge) {
+			continue
+		}
+		seen[edge.Callee.Func] = true
+		callees = append(callees, edge.Callee.Func)
+	}
+	sort.Slice(callees, func(i, j int) bool {
+		return callees[i].String() < callees[j].String()
+	})
+	return callees
+}
+
+// isPackageInit reports whether fn is a package initializer.
+func isPackageInit(fn *ssa.Function) bool {
+	return fn.Name() == "init" || strings.HasPrefix(fn.Name(), "init#")
+}
+
+// functionPackagePath returns the import path of the package declaring fn,
+// looking through generic instantiations and wrappers.
+func functionPackagePath(fn *ssa.Function) string {
+	if fn.Pkg != nil && fn.Pkg.Pkg != nil {
+		return fn.Pkg.Pkg.Path()
+	}
+	if origin := fn.Origin(); origin != nil && origin.Pkg != nil && origin.Pkg.Pkg != nil {
+		return origin.Pkg.Pkg.Path()
+	}
+	if obj := fn.Object(); obj != nil && obj.Pkg() != nil {
+		return obj.Pkg().Path()
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+// reachabilityFiles is a program whose exported API calls into dep/a, whose
+// unexported code calls into dep/b and which imports dep/c for its
+// initializer only.
+var reachabilityFiles = map[string]string{
+	"app/app.go": `package app
+
+import (
+	"example.com/dep/a"
+	"example.com/dep/b"
+	_ "example.com/dep/c"
+)
+
+func Run() { a.Do() }
+
+type Server struct{}
+
+func (*Server) Serve() { a.Serve() }
+
+func helper() { b.Do() }
+`,
+	"dep/a/a.go": `package a
+
+func Do()    { do() }
+func do()    {}
+func Serve() {}
+`,
+	"dep/b/b.go": `package b
+
+func Do() {}
+`,
+	"dep/c/c.go": `package c
+
+func init() {}
+`,
+}
+
+func TestReachability(t *testing.T) {
+	_, pkgs, cg := testProgram(t, reachabilityFiles)
+	roots := ReachabilityRoots(pkgs["example.com/app"])
+
+	tests := []struct {
+		name string
+		deps []Dependency
+		want []ModuleReachability
+	}{
+		{
+			name: "exported API",
+			deps: []Dependency{{OriginalLabel: "@a//:a", Name: "a", Version: "v1.0.0", ImportPath: "example.com/dep/a"}},
+			want: []ModuleReachability{{
+				Label:      "@a//:a",
+				Name:       "a",
+				Version:    "v1.0.0",
+				ImportPath: "example.com/dep/a",
+				Reachable:  true,
+				Functions:  []string{"example.com/dep/a.Do", "example.com/dep/a.Serve", "example.com/dep/a.do", "example.com/dep/a.init"},
+				Witness:    []string{"example.com/app.Run", "example.com/dep/a.Do"},
+			}},
+		},
+		{
+			name: "initializer only",
+			deps: []Dependency{{Name: "b", ImportPath: "example.com/dep/b"}, {Name: "c", ImportPath: "example.com/dep/c"}},
+			want: []ModuleReachability{
+				{
+					Name:       "b",
+					ImportPath: "example.com/dep/b",
+					Reachable:  true,
+					Functions:  []string{"example.com/dep/b.init"},
+					Witness:    []string{"example.com/app.init", "example.com/dep/b.init"},
+				},
+				{
+					Name:       "c",
+					ImportPath: "example.com/dep/c",
+					Reachable:  true,
+					Functions:  []string{"example.com/dep/c.init", "example.com/dep/c.init#1"},
+					Witness:    []string{"example.com/app.init", "example.com/dep/c.init"},
+				},
+			},
+		},
+		{
+			name: "most specific import path",
+			deps: []Dependency{{Name: "dep", ImportPath: "example.com/dep"}, {Name: "a", ImportPath: "example.com/dep/a"}},
+			want: []ModuleReachability{
+				{
+					Name:       "dep",
+					ImportPath: "example.com/dep",
+					Reachable:  true,
+					Functions:  []string{"example.com/dep/b.init", "example.com/dep/c.init", "example.com/dep/c.init#1"},
+					Witness:    []string{"example.com/app.init", "example.com/dep/b.init"},
+				},
+				{
+					Name:       "a",
+					ImportPath: "example.com/dep/a",
+					Reachable:  true,
+					Functions:  []string{"example.com/dep/a.Do", "example.com/dep/a.Serve", "example.com/dep/a.do", "example.com/dep/a.init"},
+					Witness:    []string{"example.com/app.Run", "example.com/dep/a.Do"},
+				},
+			},
+		},
+		{
+			name: "not imported",
+			deps: []Dependency{{Name: "other", ImportPath: "example.com/other"}},
+			want: []ModuleReachability{{Name: "other", ImportPath: "example.com/other", Functions: []string{}}},
+		},
+		{
+			name: "internal and unnamed dependencies",
+			deps: []Dependency{{Name: "a", ImportPath: "example.com/dep/a", Internal: true}, {}},
+			want: []ModuleReachability{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Reachability(cg, roots, tt.deps)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Reachability =\n  %+v\nwant\n  %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/example/go-aspects/aspects/golang/common/cgbin"
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+)
+
+// FunctionInfo describes a function in the call graph.
+type FunctionInfo struct {
+	// ID is the canonical identifier described by FunctionID
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Receiver is the receiver type of methods, e.g. *example.com/web.Server
+	Receiver   string   `json:"receiver,omitempty"`
+	Package    string   `json:"package"`
+	Signature  string   `json:"signature"`
+	Parameters []string `json:"parameters"`
+	Returns    []string `json:"returns"`
+	// TypeParams lists the type parameters of generic functions and of the
+	// methods of generic types, e.g. "T any".
+	TypeParams []string `json:"type_params,omitempty"`
+	// Origin is the ID of the generic function a generic instance was
+	// instantiated from, TypeArgs its type arguments and InstantiatedAt the
+	// file:line:column positions where the source instantiates it.
+	Origin         string   `json:"origin,omitempty"`
+	TypeArgs       []string `json:"type_args,omitempty"`
+	InstantiatedAt []string `json:"instantiated_at,omitempty"`
+	// Coverage is covered or uncovered in test mode, depending on whether
+	// a static call path leads to the function from a test.
+	Coverage string `json:"coverage,omitempty"`
+}
+
+// CallEdge is a single caller to callee relationship at one call site, as
+// the call_edges section of a JSON result and package summaries write it.
+type CallEdge struct {
+	Caller FunctionInfo `json:"caller"`
+	Callee FunctionInfo `json:"callee"`
+	// Position of the call instruction in the caller
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+	// Kind is static, dynamic or interface; Mode is call, go or defer.
+	Kind string `json:"kind,omitempty"`
+	Mode string `json:"mode,omitempty"`
+	// ReceiverType is the interface type of an interface method call.
+	ReceiverType string `json:"receiver_type,omitempty"`
+	// Scope is internal for calls between workspace packages, else external.
+	Scope string `json:"scope"`
+}
+
+// NormalizedEdge is a call edge referencing its caller and callee by
+// function ID instead of repeating their FunctionInfo. Results keep their
+// edges normalized; the functions section holds the FunctionInfo of both
+// ends.
+type NormalizedEdge struct {
+	Caller       string `json:"caller"`
+	Callee       string `json:"callee"`
+	File         string `json:"file,omitempty"`
+	Line         int    `json:"line,omitempty"`
+	Column       int    `json:"column,omitempty"`
+	Kind         string `json:"kind,omitempty"`
+	Mode         string `json:"mode,omitempty"`
+	ReceiverType string `json:"receiver_type,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// normalizeEdge drops the function information of an edge.
+func normalizeEdge(edge CallEdge) NormalizedEdge {
+	return NormalizedEdge{
+		Caller:       edge.Caller.ID,
+		Callee:       edge.Callee.ID,
+		File:         edge.File,
+		Line:         edge.Line,
+		Column:       edge.Column,
+		Kind:         edge.Kind,
+		Mode:         edge.Mode,
+		ReceiverType: edge.ReceiverType,
+		Scope:        edge.Scope,
+	}
+}
+
+// CallGraphResult is the output contract shared by every callgraph aspect.
+// Its edges are kept normalized in memory; its JSON encoding repeats the
+// FunctionInfo of the caller and callee in every call edge.
+type CallGraphResult struct {
+	// SchemaVersion is the version of the callgraph_result schema the
+	// result follows
+	SchemaVersion int                     `json:"schema_version"`
+	PackageID     string                  `json:"package_id"`
+	PackageName   string                  `json:"package_name"`
+	ImportPath    string                  `json:"import_path"`
+	CallGraph     map[string][]string     `json:"call_graph"` // Legacy format, keyed by function ID
+	Functions     map[string]FunctionInfo `json:"functions"`  // Function signatures, keyed by function ID
+	CallEdges     []NormalizedEdge        `json:"call_edges"` // Enhanced call relationships
+	TotalFuncs    int                     `json:"total_functions"`
+	TotalEdges    int                     `json:"total_edges"`
+	Algorithm     string                  `json:"algorithm"`
+	// DiscoveredEntrypoints are the HTTP handlers, gRPC methods and
+	// goroutines the workspace packages register, with their routes.
+	DiscoveredEntrypoints []Entrypoint `json:"discovered_entrypoints,omitempty"`
+	// Entrypoints and Reachability are only set when dependencies are
+	// given for reachability analysis, Vulnerabilities when advisories are.
+	Entrypoints     []string             `json:"entrypoints,omitempty"`
+	Reachability    []ModuleReachability `json:"reachability,omitempty"`
+	Vulnerabilities []VulnFinding        `json:"vulnerabilities,omitempty"`
+	// UnusedDependencies lists the dependencies without reachable calls
+	UnusedDependencies []UnusedDependency `json:"unused_dependencies,omitempty"`
+	// TestEntrypoints and Untested are only set in test mode. Untested
+	// lists the workspace functions reachable from the entrypoints and the
+	// exported API without a call path from any test.
+	TestEntrypoints []string `json:"test_entrypoints,omitempty"`
+	Untested        []string `json:"untested,omitempty"`
+	// Exposure maps the discovered routes to the dependencies they reach;
+	// it is only set when the exposure report is enabled
+	Exposure []RouteExposure `json:"exposure,omitempty"`
+	// TaintFlows are the source-to-sink data flows; they are only set in
+	// taint mode
+	TaintFlows []TaintFlow `json:"taint_flows,omitempty"`
+	// PackageGraph and ModuleGraph are only set when aggregation is enabled
+	PackageGraph []AggregateEdge `json:"package_graph,omitempty"`
+	ModuleGraph  []AggregateEdge `json:"module_graph,omitempty"`
+	// Diagnostics are the load errors, type errors, skipped packages and
+	// fallbacks met during the analysis. A result with error diagnostics
+	// is missing calls, even when its call graph is empty.
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// NewResult returns an empty result for the given root package, which may
+// be nil when the packages JSON contains no workspace package.
+func NewResult(root *PackageJSON) *CallGraphResult {
+	result := &CallGraphResult{
+		SchemaVersion: schema.Version,
+		CallGraph:     make(map[string][]string),
+		Functions:     make(map[string]FunctionInfo),
+		CallEdges:     []NormalizedEdge{},
+		Algorithm:     "VTA",
+	}
+	if root != nil {
+		result.PackageID = root.ID
+		result.PackageName = root.Name
+		result.ImportPath = root.PkgPath
+	}
+	return result
+}
+
+// Edge returns the edge with the FunctionInfo of its caller and callee,
+// failing when the functions section lacks either.
+func (r *CallGraphResult) Edge(edge NormalizedEdge) (CallEdge, error) {
+	caller, ok := r.Functions[edge.Caller]
+	if !ok {
+		return CallEdge{}, fmt.Errorf("edge references unknown caller %s", edge.Caller)
+	}
+	callee, ok := r.Functions[edge.Callee]
+	if !ok {
+		return CallEdge{}, fmt.Errorf("edge references unknown callee %s", edge.Callee)
+	}
+	return CallEdge{
+		Caller:       caller,
+		Callee:       callee,
+		File:         edge.File,
+		Line:         edge.Line,
+		Column:       edge.Column,
+		Kind:         edge.Kind,
+		Mode:         edge.Mode,
+		ReceiverType: edge.ReceiverType,
+		Scope:        edge.Scope,
+	}, nil
+}
+
+// callGraphResult has the fields of CallGraphResult without its JSON
+// methods.
+type callGraphResult CallGraphResult
+
+// MarshalJSON encodes the result with the FunctionInfo of both ends in
+// every call edge.
+func (r CallGraphResult) MarshalJSON() ([]byte, error) {
+	var edges []CallEdge
+	if r.CallEdges != nil {
+		edges = make([]CallEdge, 0, len(r.CallEdges))
+	}
+	for _, edge := range r.CallEdges {
+		full, err := r.Edge(edge)
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, full)
+	}
+	return json.Marshal(struct {
+		*callGraphResult
+		CallEdges []CallEdge `json:"call_edges"`
+	}{(*callGraphResult)(&r), edges})
+}
+
+// UnmarshalJSON decodes a result, normalizing its call edges. Callers and
+// callees missing from the functions section are added to it.
+func (r *CallGraphResult) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		*callGraphResult
+		CallEdges []CallEdge `json:"call_edges"`
+	}
+	decoded.callGraphResult = (*callGraphResult)(r)
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.CallEdges == nil {
+		r.CallEdges = nil
+		return nil
+	}
+	if r.Functions == nil {
+		r.Functions = make(map[string]FunctionInfo)
+	}
+	r.CallEdges = make([]NormalizedEdge, 0, len(decoded.CallEdges))
+	for _, edge := range decoded.CallEdges {
+		for _, info := range []FunctionInfo{edge.Caller, edge.Callee} {
+			if _, ok := r.Functions[info.ID]; !ok {
+				r.Functions[info.ID] = info
+			}
+		}
+		r.CallEdges = append(r.CallEdges, normalizeEdge(edge))
+	}
+	return nil
+}
+
+// ReadResult reads a CallGraphResult written in any output format: a JSON,
+// NDJSON or binary file, or a sharded result directory.
+func ReadResult(path string) (*CallGraphResult, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		sharded, err := OpenShardedResult(path)
+		if err != nil {
+			return nil, err
+		}
+		return sharded.Result()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result file: %v", err)
+	}
+
+	if cgbin.IsBinary(data) {
+		result, err := DecodeBinary(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse result file %s: %v", path, err)
+		}
+		return result, nil
+	}
+
+	// Normalized results start with a header record
+	var first Record
+	if json.NewDecoder(bytes.NewReader(data)).Decode(&first) == nil && first.Type == RecordHeader {
+		result, err := readNDJSON(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse result file %s: %v", path, err)
+		}
+		return result, nil
+	}
+
+	var result CallGraphResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse result file %s: %v", path, err)
+	}
+	return &result, nil
+}
+
+// WriteResult validates the result against the callgraph_result schema and
+// writes it as indented JSON, creating the output directory if needed.
+func WriteResult(outputFile string, result *CallGraphResult) error {
+	return writeJSON(outputFile, result, schema.CallGraphResult)
+}
+
+// WriteDiff validates the diff against the callgraph_diff schema and writes
+// it as indented JSON, creating the output directory if needed.
+func WriteDiff(outputFile string, diff *ResultDiff) error {
+	return writeJSON(outputFile, diff, schema.CallGraphDiff)
+}
+
+// writeJSON validates v against schemaRef and writes it as indented JSON,
+// so a malformed output fails instead of being written.
+func writeJSON(outputFile string, v interface{}, schemaRef string) error {
+	// Ensure output directory exists
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	resultData, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+
+	if err := schema.Validate(schemaRef, resultData); err != nil {
+		return fmt.Errorf("invalid output %s: %v", outputFile, err)
+	}
+
+	if err := os.WriteFile(outputFile, resultData, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// testRoot is the root package of the test results; packages below it are
+// internal.
+const testRoot = "example.com/app"
+
+// testFunction returns the FunctionInfo of a package-level function ID
+// without parameters or results, as Extract writes it.
+func testFunction(id string) FunctionInfo {
+	i := strings.LastIndex(id, ".")
+	return FunctionInfo{ID: id, Name: id[i+1:], Package: id[:i], Signature: "func()"}
+}
+
+// testResult returns a result of the root package with "caller -> callee"
+// static edges, as Extract writes them.
+func testResult(edges ...string) *CallGraphResult {
+	result := NewResult(&PackageJSON{ID: "//app:app", Name: "main", PkgPath: testRoot})
+	internal := func(pkg string) bool { return pkg == testRoot || strings.HasPrefix(pkg, testRoot+"/") }
+	for i, e := range edges {
+		caller, callee, _ := strings.Cut(e, " -> ")
+		edge := CallEdge{Caller: testFunction(caller), Callee: testFunction(callee), File: "main.go", Line: i + 1, Column: 2, Kind: CallStatic, Mode: ModeCall, Scope: ScopeExternal}
+		if internal(edge.Caller.Package) && internal(edge.Callee.Package) {
+			edge.Scope = ScopeInternal
+		}
+		result.Functions[caller] = edge.Caller
+		result.Functions[callee] = edge.Callee
+		result.CallGraph[caller] = append(result.CallGraph[caller], callee)
+		result.CallEdges = append(result.CallEdges, normalizeEdge(edge))
+	}
+	result.TotalFuncs = len(result.CallGraph)
+	result.TotalEdges = len(result.CallEdges)
+	return result
+}
+
+// testProgram writes the files, keyed by their path below the module root,
+// to a module named example.com unless they hold its go.mod, and returns
+// its SSA program, the SSA packages by import path and the CHA call graph
+// of the program.
+func testProgram(t *testing.T, files map[string]string) (*ssa.Program, map[string]*ssa.Package, *callgraph.Graph) {
+	t.Helper()
+	dir := t.TempDir()
+	if _, ok := files["go.mod"]; !ok {
+		files["go.mod"] = "module example.com\n\ngo 1.23\n"
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prog, initial, failed, err := BuildProgram(loadPackages(t, dir, "./..."), AlgorithmCHA.builderMode(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) > 0 {
+		t.Fatalf("SSA construction failed for %d packages", len(failed))
+	}
+	pkgs := make(map[string]*ssa.Package)
+	for _, pkg := range initial {
+		pkgs[pkg.Pkg.Path()] = pkg
+	}
+	cg, err := BuildCallGraph(prog, AlgorithmCHA, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prog, pkgs, cg
+}
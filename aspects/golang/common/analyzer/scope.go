@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Edge scopes recorded on call edges.
+const (
+	// ScopeInternal is a call between two workspace packages.
+	ScopeInternal = "internal"
+	// ScopeExternal is a call involving a package outside the workspace,
+	// such as a third-party module or the standard library.
+	ScopeExternal = "external"
+)
+
+// Scope selects the callers kept in a result and classifies packages as
+// internal to the workspace or external.
+type Scope struct {
+	// RootPath is the import path of the root package.
+	RootPath string
+	// RootOnly keeps calls made from the root package, which is the default
+	// when no other selection is made.
+	RootOnly bool
+	// Internal keeps calls made from any workspace package.
+	Internal bool
+	// Prefixes keeps calls made from packages matching an import path
+	// prefix.
+	Prefixes []string
+	// All keeps calls made from every package, the standard library
+	// included.
+	All bool
+
+	internal map[string]bool
+}
+
+// NewScope returns the scope configured by cfg. Workspace packages are the
+// loaded packages of the main module, the packages matching a source
+// package of the response, and the internal dependencies.
+func NewScope(cfg *Config, rootPath string, pkgs []*packages.Package, response *PackagesResponse) *Scope {
+	s := &Scope{
+		RootPath: rootPath,
+		RootOnly: cfg.RootOnly,
+		Internal: cfg.Internal,
+		Prefixes: cfg.Packages,
+		All:      cfg.AllPackages,
+		internal: make(map[string]bool),
+	}
+	if rootPath != "" {
+		s.internal[rootPath] = true
+	}
+
+	var sourcePaths []string
+	for _, pkg := range response.SourcePackages() {
+		if pkg.PkgPath != "" {
+			sourcePaths = append(sourcePaths, pkg.PkgPath)
+		}
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Module != nil && pkg.Module.Main {
+			s.internal[pkg.PkgPath] = true
+			return
+		}
+		// The workspace loader reports Bazel package paths such as
+		// "src/main", so also match on the import path suffix
+		for _, path := range sourcePaths {
+			if pkg.PkgPath == path || strings.HasSuffix(pkg.PkgPath, "/"+path) {
+				s.internal[pkg.PkgPath] = true
+				return
+			}
+		}
+	})
+	for _, dep := range cfg.Dependencies {
+		if dep.Internal && dep.ImportPath != "" {
+			s.internal[dep.ImportPath] = true
+		}
+	}
+	return s
+}
+
+// setRoot makes the package at rootPath the root package, as when the
+// analysis falls back to another package than the requested root.
+func (s *Scope) setRoot(rootPath string) {
+	s.RootPath = rootPath
+	if rootPath != "" {
+		s.internal[rootPath] = true
+	}
+}
+
+// IsInternal reports whether the package belongs to the workspace.
+func (s *Scope) IsInternal(pkgPath string) bool {
+	return s.internal[pkgPath]
+}
+
+// Keeps reports whether calls made from the package belong in the result.
+// Without any selection only the calls made from the root package are kept,
+// or every call when there is no root package.
+func (s *Scope) Keeps(pkgPath string) bool {
+	selected := s.Internal || len(s.Prefixes) > 0
+	rootOnly := (s.RootOnly || !selected) && s.RootPath != ""
+	if s.All || !rootOnly && !selected {
+		return true
+	}
+	if rootOnly && pkgPath == s.RootPath {
+		return true
+	}
+	if s.Internal && s.IsInternal(pkgPath) {
+		return true
+	}
+	return matchesPackage(pkgPath, s.Prefixes)
+}
+
+// EdgeScope classifies a call between two packages.
+func (s *Scope) EdgeScope(callerPkg, calleePkg string) string {
+	if s.IsInternal(callerPkg) && s.IsInternal(calleePkg) {
+		return ScopeInternal
+	}
+	return ScopeExternal
+}
@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// stdlibLoadMode loads packages from their compiler export data: without
+// NeedSyntax, go/packages reads types from the export files of go list.
+const stdlibLoadMode = packages.NeedName | packages.NeedImports |
+	packages.NeedExportFile | packages.NeedTypes | packages.NeedTypesSizes
+
+// IsStdlibPackage reports whether a package ID refers to a standard library
+// package of the Go SDK, such as "@@rules_go~//stdlib:fmt" or
+// "@//stdlib:net/http".
+func IsStdlibPackage(id string) bool {
+	return strings.Contains(id, "//stdlib:")
+}
+
+// StdlibPackages returns the import paths of the standard library. They are
+// taken from the stdlib package IDs of the driver response when it has any,
+// and otherwise from `go list std` of the Go SDK in the environment.
+func StdlibPackages(response *PackagesResponse, env []string) (map[string]bool, error) {
+	std := make(map[string]bool)
+	for _, pkg := range response.Packages {
+		if IsStdlibPackage(pkg.ID) && pkg.PkgPath != "" {
+			std[pkg.PkgPath] = true
+		}
+	}
+	if len(std) > 0 {
+		return std, nil
+	}
+
+	cmd := exec.Command("go", "list", "std")
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list std failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			std[line] = true
+		}
+	}
+	return std, nil
+}
+
+// loadStdlibExportData loads the named standard library packages from their
+// export data in a single go list invocation. Packages are keyed by import
+// path.
+func loadStdlibExportData(paths []string, env []string) (map[string]*packages.Package, error) {
+	loaded := make(map[string]*packages.Package)
+	if len(paths) == 0 {
+		return loaded, nil
+	}
+
+	cfg := &packages.Config{
+		Mode: stdlibLoadMode,
+		Env:  env,
+	}
+	pkgs, err := packages.Load(cfg, paths...)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range pkgs {
+		if pkg.Types != nil {
+			loaded[pkg.PkgPath] = pkg
+		}
+	}
+	return loaded, nil
+}
@@ -0,0 +1,463 @@
+package analyzer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+)
+
+// OutputFormat selects how a result is written.
+type OutputFormat string
+
+const (
+	// OutputJSON writes the result as a single indented JSON document.
+	OutputJSON OutputFormat = "json"
+	// OutputNDJSON writes the normalized result as one JSON record per line:
+	// a header holding the reports, then the functions and the edges, which
+	// reference their caller and callee by function ID.
+	OutputNDJSON OutputFormat = "ndjson"
+	// OutputSharded writes a directory holding a manifest with the header
+	// and one NDJSON shard per package with the package's functions and the
+	// edges they call.
+	OutputSharded OutputFormat = "sharded"
+	// OutputBinary writes the compact binary encoding of the cgbin package.
+	OutputBinary OutputFormat = "binary"
+)
+
+// ParseOutputFormat validates an output format name.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch f := OutputFormat(strings.ToLower(s)); f {
+	case OutputJSON, OutputNDJSON, OutputSharded, OutputBinary:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (want json, ndjson, sharded or binary)", s)
+	}
+}
+
+// Record types of the normalized formats.
+const (
+	RecordHeader   = "header"
+	RecordFunction = "function"
+	RecordEdge     = "edge"
+)
+
+// ShardManifestFile is the name of the manifest of a sharded result.
+const ShardManifestFile = "manifest.json"
+
+// Record is one line of a normalized result. Exactly one of Header,
+// Function and Edge is set, as given by Type.
+type Record struct {
+	Type string `json:"type"`
+	// Header is the result without its call_graph, functions and
+	// call_edges sections
+	Header   *CallGraphResult `json:"header,omitempty"`
+	Function *FunctionInfo    `json:"function,omitempty"`
+	Edge     *NormalizedEdge  `json:"edge,omitempty"`
+}
+
+// ShardManifest describes a sharded result.
+type ShardManifest struct {
+	Header *CallGraphResult `json:"header"`
+	Shards []ShardInfo      `json:"shards"`
+}
+
+// ShardInfo locates the shard of one package, relative to the manifest.
+type ShardInfo struct {
+	Package   string `json:"package"`
+	File      string `json:"file"`
+	Functions int    `json:"functions"`
+	Edges     int    `json:"edges"`
+}
+
+// resultHeader returns a copy of the result without its graph sections.
+func resultHeader(result *CallGraphResult) *CallGraphResult {
+	header := *result
+	header.CallGraph = nil
+	header.Functions = nil
+	header.CallEdges = nil
+	return &header
+}
+
+// StreamWriter writes a normalized result incrementally as NDJSON.
+// Functions are written once, before the first edge referencing them.
+// Each record is validated against the callgraph_record schema before it
+// is written.
+type StreamWriter struct {
+	w       *bufio.Writer
+	written map[string]bool
+}
+
+// NewStreamWriter returns a writer of NDJSON records to w. Flush must be
+// called once all records are written.
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	return &StreamWriter{w: bufio.NewWriter(w), written: make(map[string]bool)}
+}
+
+// write validates a record and writes it as one line.
+func (s *StreamWriter) write(record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := schema.Validate(schema.CallGraphRecord, data); err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = s.w.Write(data)
+	return err
+}
+
+// WriteHeader writes the reports and totals of the result.
+func (s *StreamWriter) WriteHeader(result *CallGraphResult) error {
+	return s.write(Record{Type: RecordHeader, Header: resultHeader(result)})
+}
+
+// WriteFunction writes a function unless it was already written.
+func (s *StreamWriter) WriteFunction(info FunctionInfo) error {
+	if s.written[info.ID] {
+		return nil
+	}
+	s.written[info.ID] = true
+	return s.write(Record{Type: RecordFunction, Function: &info})
+}
+
+// WriteEdge writes an edge, preceded by its caller and callee, taken from
+// functions, if they were not written yet.
+func (s *StreamWriter) WriteEdge(edge NormalizedEdge, functions map[string]FunctionInfo) error {
+	for _, id := range []string{edge.Caller, edge.Callee} {
+		if s.written[id] {
+			continue
+		}
+		info, ok := functions[id]
+		if !ok {
+			return fmt.Errorf("edge references unknown function %s", id)
+		}
+		if err := s.WriteFunction(info); err != nil {
+			return err
+		}
+	}
+	return s.write(Record{Type: RecordEdge, Edge: &edge})
+}
+
+// Flush writes any buffered records.
+func (s *StreamWriter) Flush() error {
+	return s.w.Flush()
+}
+
+// writeRecords writes the header, when given, and then the edges and the
+// remaining functions of the result.
+func writeRecords(s *StreamWriter, header *CallGraphResult, functions map[string]FunctionInfo, edges []NormalizedEdge) error {
+	if header != nil {
+		if err := s.WriteHeader(header); err != nil {
+			return err
+		}
+	}
+	for _, edge := range edges {
+		if err := s.WriteEdge(edge, functions); err != nil {
+			return err
+		}
+	}
+	ids := make([]string, 0, len(functions))
+	for id := range functions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := s.WriteFunction(functions[id]); err != nil {
+			return err
+		}
+	}
+	return s.Flush()
+}
+
+// WriteResultFormat writes the result in the given format, creating the
+// output directory if needed. The sharded format writes outputFile as a
+// directory.
+func WriteResultFormat(outputFile string, result *CallGraphResult, format OutputFormat) error {
+	switch format {
+	case OutputNDJSON:
+		return writeNDJSON(outputFile, result)
+	case OutputSharded:
+		return writeShards(outputFile, result)
+	case OutputBinary:
+		return writeBinary(outputFile, result)
+	default:
+		return WriteResult(outputFile, result)
+	}
+}
+
+func writeNDJSON(outputFile string, result *CallGraphResult) error {
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	if err := writeRecords(NewStreamWriter(file), result, result.Functions, result.CallEdges); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return file.Close()
+}
+
+// writeShards writes one shard per package: the functions of the package
+// and the edges they make.
+func writeShards(outputDir string, result *CallGraphResult) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	functions := make(map[string]map[string]FunctionInfo)
+	edges := make(map[string][]NormalizedEdge)
+	for id, info := range result.Functions {
+		if functions[info.Package] == nil {
+			functions[info.Package] = make(map[string]FunctionInfo)
+		}
+		functions[info.Package][id] = info
+	}
+	for _, edge := range result.CallEdges {
+		caller, ok := result.Functions[edge.Caller]
+		if !ok {
+			return fmt.Errorf("edge references unknown caller %s", edge.Caller)
+		}
+		edges[caller.Package] = append(edges[caller.Package], edge)
+	}
+
+	pkgs := make([]string, 0, len(functions))
+	for pkg := range functions {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	manifest := ShardManifest{Header: resultHeader(result), Shards: []ShardInfo{}}
+	for i, pkg := range pkgs {
+		shard := ShardInfo{
+			Package:   pkg,
+			File:      fmt.Sprintf("shard-%05d.ndjson", i),
+			Functions: len(functions[pkg]),
+			Edges:     len(edges[pkg]),
+		}
+		file, err := os.Create(filepath.Join(outputDir, shard.File))
+		if err != nil {
+			return fmt.Errorf("failed to write shard: %v", err)
+		}
+		// Only the functions of the package go in its shard; callees in
+		// other packages are found in theirs
+		s := NewStreamWriter(file)
+		for _, edge := range edges[pkg] {
+			if _, ok := functions[pkg][edge.Callee]; !ok {
+				s.written[edge.Callee] = true
+			}
+		}
+		if err := writeRecords(s, nil, functions[pkg], edges[pkg]); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write shard: %v", err)
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write shard: %v", err)
+		}
+		manifest.Shards = append(manifest.Shards, shard)
+	}
+
+	return writeJSON(filepath.Join(outputDir, ShardManifestFile), manifest, schema.ShardManifest)
+}
+
+// StreamReader reads the records of a normalized result one at a time.
+type StreamReader struct {
+	dec *json.Decoder
+}
+
+// NewStreamReader returns a reader of the NDJSON records in r.
+func NewStreamReader(r io.Reader) *StreamReader {
+	return &StreamReader{dec: json.NewDecoder(bufio.NewReader(r))}
+}
+
+// Next returns the next record, or io.EOF after the last one.
+func (r *StreamReader) Next() (*Record, error) {
+	var record Record
+	if err := r.dec.Decode(&record); err != nil {
+		return nil, err
+	}
+	switch {
+	case record.Type == RecordHeader && record.Header != nil,
+		record.Type == RecordFunction && record.Function != nil,
+		record.Type == RecordEdge && record.Edge != nil:
+		return &record, nil
+	default:
+		return nil, fmt.Errorf("invalid %q record", record.Type)
+	}
+}
+
+// Shard holds the functions of one package and the edges they make.
+type Shard struct {
+	Package   string
+	Functions map[string]FunctionInfo
+	Edges     []NormalizedEdge
+}
+
+// readShard reads the function and edge records of a stream.
+func readShard(r io.Reader, pkg string) (*Shard, *CallGraphResult, error) {
+	shard := &Shard{Package: pkg, Functions: make(map[string]FunctionInfo)}
+	var header *CallGraphResult
+	reader := NewStreamReader(r)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return shard, header, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		switch record.Type {
+		case RecordHeader:
+			header = record.Header
+		case RecordFunction:
+			shard.Functions[record.Function.ID] = *record.Function
+		case RecordEdge:
+			shard.Edges = append(shard.Edges, *record.Edge)
+		}
+	}
+}
+
+// ShardedResult reads a sharded result, loading each shard when it is
+// first needed.
+type ShardedResult struct {
+	Manifest ShardManifest
+	dir      string
+	shards   map[string]*Shard
+}
+
+// OpenShardedResult reads the manifest of a sharded result directory.
+func OpenShardedResult(dir string) (*ShardedResult, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ShardManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shard manifest: %v", err)
+	}
+	r := &ShardedResult{dir: dir, shards: make(map[string]*Shard)}
+	if err := json.Unmarshal(data, &r.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse shard manifest %s: %v", dir, err)
+	}
+	if r.Manifest.Header == nil {
+		return nil, fmt.Errorf("shard manifest %s has no header", dir)
+	}
+	return r, nil
+}
+
+// Packages returns the packages having a shard.
+func (r *ShardedResult) Packages() []string {
+	var pkgs []string
+	for _, shard := range r.Manifest.Shards {
+		pkgs = append(pkgs, shard.Package)
+	}
+	return pkgs
+}
+
+// Shard returns the shard of a package, or nil if it has none.
+func (r *ShardedResult) Shard(pkg string) (*Shard, error) {
+	if shard, ok := r.shards[pkg]; ok {
+		return shard, nil
+	}
+	for _, info := range r.Manifest.Shards {
+		if info.Package != pkg {
+			continue
+		}
+		file, err := os.Open(filepath.Join(r.dir, info.File))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read shard: %v", err)
+		}
+		defer file.Close()
+		shard, _, err := readShard(file, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse shard %s: %v", info.File, err)
+		}
+		r.shards[pkg] = shard
+		return shard, nil
+	}
+	return nil, nil
+}
+
+// Function looks up a function by ID. Function IDs contain the import path
+// of their package, so only the shards of packages the ID mentions are
+// loaded, the most specific first.
+func (r *ShardedResult) Function(id string) (FunctionInfo, bool, error) {
+	var candidates []string
+	for _, shard := range r.Manifest.Shards {
+		if strings.Contains(id, shard.Package+".") {
+			candidates = append(candidates, shard.Package)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return len(candidates[i]) > len(candidates[j]) })
+	for _, pkg := range candidates {
+		shard, err := r.Shard(pkg)
+		if err != nil {
+			return FunctionInfo{}, false, err
+		}
+		if info, ok := shard.Functions[id]; ok {
+			return info, true, nil
+		}
+	}
+	return FunctionInfo{}, false, nil
+}
+
+// Result loads every shard and assembles the complete result.
+func (r *ShardedResult) Result() (*CallGraphResult, error) {
+	shards := make([]*Shard, 0, len(r.Manifest.Shards))
+	functions := make(map[string]FunctionInfo)
+	for _, info := range r.Manifest.Shards {
+		shard, err := r.Shard(info.Package)
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, shard)
+		for id, fn := range shard.Functions {
+			functions[id] = fn
+		}
+	}
+	var edges []NormalizedEdge
+	for _, shard := range shards {
+		edges = append(edges, shard.Edges...)
+	}
+	return assemble(r.Manifest.Header, functions, edges)
+}
+
+// assemble rebuilds a result from its header, functions and edges. The
+// call_graph section is rebuilt from the edges.
+func assemble(header *CallGraphResult, functions map[string]FunctionInfo, edges []NormalizedEdge) (*CallGraphResult, error) {
+	result := *header
+	result.CallGraph = make(map[string][]string)
+	result.Functions = functions
+	result.CallEdges = edges
+	if edges == nil {
+		result.CallEdges = []NormalizedEdge{}
+	}
+	for _, edge := range edges {
+		if _, ok := functions[edge.Caller]; !ok {
+			return nil, fmt.Errorf("edge references unknown caller %s", edge.Caller)
+		}
+		if _, ok := functions[edge.Callee]; !ok {
+			return nil, fmt.Errorf("edge references unknown callee %s", edge.Callee)
+		}
+		result.CallGraph[edge.Caller] = append(result.CallGraph[edge.Caller], edge.Callee)
+	}
+	return &result, nil
+}
+
+// readNDJSON reads a complete NDJSON result.
+func readNDJSON(r io.Reader) (*CallGraphResult, error) {
+	shard, header, err := readShard(r, "")
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("no header record")
+	}
+	return assemble(header, shard.Functions, shard.Edges)
+}
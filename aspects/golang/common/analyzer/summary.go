@@ -0,0 +1,482 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// PackageSummary is the call graph of a single package, computed without
+// the bodies of its dependencies so that it can be cached per Bazel
+// target. Summaries of the packages of a binary are linked into its call
+// graph by LinkSummaries.
+type PackageSummary struct {
+	// SchemaVersion is the version of the callgraph_summary schema the
+	// summary follows
+	SchemaVersion int    `json:"schema_version"`
+	PackageID     string `json:"package_id"`
+	PackageName   string `json:"package_name"`
+	ImportPath    string `json:"import_path"`
+	// Functions are the functions of the package, including closures and
+	// the generic instances it creates
+	Functions map[string]FunctionInfo `json:"functions"`
+	// Edges are the calls made by the functions of the package whose
+	// callee is known statically
+	Edges []CallEdge `json:"edges"`
+	// Unresolved are the calls through interfaces and function values,
+	// resolved against all summaries when they are linked
+	Unresolved []UnresolvedCall `json:"unresolved"`
+	// Types are the method sets of the named types the package declares,
+	// the candidate callees of interface calls
+	Types []TypeMethods `json:"types"`
+	// AddressTaken are the functions the package uses as values, the
+	// candidate callees of calls through function values
+	AddressTaken []FunctionValue `json:"address_taken"`
+	// Diagnostics are the problems met while summarizing the package,
+	// carried over to the linked result
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// UnresolvedCall is a call site whose callee depends on the dynamic type of
+// an interface or on a function value.
+type UnresolvedCall struct {
+	Caller FunctionInfo `json:"caller"`
+	File   string       `json:"file,omitempty"`
+	Line   int          `json:"line,omitempty"`
+	Column int          `json:"column,omitempty"`
+	// Kind is interface or dynamic; Mode is call, go or defer.
+	Kind string `json:"kind"`
+	Mode string `json:"mode"`
+	// ReceiverType, Method and Interface describe interface calls: the
+	// interface type, the called method and every method of the interface,
+	// which a type must have to be a callee
+	ReceiverType string            `json:"receiver_type,omitempty"`
+	Method       string            `json:"method,omitempty"`
+	Interface    []MethodSignature `json:"interface,omitempty"`
+	// Signature is the type of the called function value of dynamic calls
+	Signature string `json:"signature,omitempty"`
+}
+
+// MethodSignature is a method name with its signature, without receiver.
+type MethodSignature struct {
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+}
+
+// TypeMethods is the method set of a pointer to a named type.
+type TypeMethods struct {
+	Type    string         `json:"type"`
+	Methods []MethodSource `json:"methods"`
+}
+
+// MethodSource is a method of a method set and the function declaring it,
+// which may belong to an embedded type of another package.
+type MethodSource struct {
+	MethodSignature
+	Function FunctionInfo `json:"function"`
+}
+
+// FunctionValue is a function used as a value, with its signature.
+type FunctionValue struct {
+	Function  FunctionInfo `json:"function"`
+	Signature string       `json:"signature"`
+}
+
+// Summarize loads the packages of the response and summarizes the root
+// package. Dependencies are only needed for their types, so the export
+// data loader suffices: the packages JSON of the aspects lists the direct
+// imports of the root and the export files of every dependency. A root
+// without Go files has an empty summary.
+func Summarize(cfg *Config, response *PackagesResponse) (*PackageSummary, error) {
+	cfg.diagnostics = nil
+	root := response.RootPackage()
+	if root == nil {
+		return nil, fmt.Errorf("no workspace package in the packages JSON")
+	}
+	if withoutGoFiles(cfg, root) {
+		cfg.logf("📭 %s has no Go files to summarize\n", root.ID)
+		return newSummary(root.ID, root.Name, root.PkgPath), nil
+	}
+
+	pkgs, err := Load(cfg, response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %v", err)
+	}
+	scope := NewScope(cfg, root.PkgPath, pkgs, response)
+	validPackages := filterValidPackages(pkgs)
+	reportPackages(cfg, pkgs, validPackages, scope.IsInternal)
+	if len(validPackages) == 0 {
+		return nil, fmt.Errorf("no valid packages for SSA analysis")
+	}
+
+	// Summaries list the generic instances the package creates
+	prog, initial, failed, err := BuildProgram(validPackages, AlgorithmStatic.builderMode(true))
+	reportBuildFailures(cfg, failed, scope.IsInternal)
+	if err != nil {
+		return nil, err
+	}
+	defer cacheClosureLabels(prog)()
+	rootPkg, matched := findRootPackage(initial, root.PkgPath)
+	if rootPkg == nil {
+		return nil, fmt.Errorf("no SSA package built for %s", root.ID)
+	}
+	if !matched {
+		cfg.diagnose(SeverityWarning, DiagnosticFallback, root.ID, "no SSA package built for the root package %q, summarizing %s instead", root.PkgPath, rootPkg.Pkg.Path())
+	}
+
+	scope.setRoot(rootPkg.Pkg.Path())
+	summary := SummarizePackage(prog, rootPkg, scope)
+	summary.PackageID = root.ID
+	summary.Diagnostics = cfg.diagnostics
+	cfg.logf("🧾 Summarized %s: %d functions, %d static edges, %d unresolved calls, %d types, %d function values\n",
+		summary.ImportPath, len(summary.Functions), len(summary.Edges), len(summary.Unresolved), len(summary.Types), len(summary.AddressTaken))
+	return summary, nil
+}
+
+// newSummary returns the summary of a package without functions.
+func newSummary(id, name, pkgPath string) *PackageSummary {
+	return &PackageSummary{
+		SchemaVersion: schema.Version,
+		PackageID:     id,
+		PackageName:   name,
+		ImportPath:    pkgPath,
+		Functions:     make(map[string]FunctionInfo),
+		Edges:         []CallEdge{},
+		Unresolved:    []UnresolvedCall{},
+		Types:         []TypeMethods{},
+		AddressTaken:  []FunctionValue{},
+	}
+}
+
+// SummarizePackage summarizes the functions, calls and method sets of the
+// package. Synthetic functions are left out except the package initializer,
+// which calls the initializers of the imports. Calls to wrappers of promoted
+// and bound methods are recorded as calls to the methods they wrap.
+func SummarizePackage(prog *ssa.Program, pkg *ssa.Package, scope *Scope) *PackageSummary {
+	pkgPath := pkg.Pkg.Path()
+	summary := newSummary(pkgPath, pkg.Pkg.Name(), pkgPath)
+
+	var fns []*ssa.Function
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Blocks != nil && (fn.Synthetic == "" || fn.Origin() != nil || isPackageInit(fn)) && functionPackagePath(fn) == pkgPath {
+			fns = append(fns, fn)
+		}
+	}
+	sort.Slice(fns, func(i, j int) bool {
+		return FunctionID(fns[i]) < FunctionID(fns[j])
+	})
+
+	taken := make(map[string]bool)
+	for _, fn := range fns {
+		caller := functionInfo(fn)
+		summary.Functions[caller.ID] = caller
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				if site, ok := instr.(ssa.CallInstruction); ok {
+					summarizeCall(summary, scope, fn, caller, site)
+				}
+				for _, value := range functionOperands(instr) {
+					target := declaredFunction(value)
+					info := functionInfo(target)
+					if !taken[info.ID] {
+						taken[info.ID] = true
+						summary.AddressTaken = append(summary.AddressTaken, FunctionValue{
+							Function:  info,
+							Signature: signatureKey(value.Signature),
+						})
+					}
+				}
+			}
+		}
+	}
+
+	var names []string
+	for name := range pkg.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		member, ok := pkg.Members[name].(*ssa.Type)
+		if !ok {
+			continue
+		}
+		named, ok := member.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 || types.IsInterface(named) {
+			continue
+		}
+		ptr := types.NewPointer(named)
+		methods := TypeMethods{Type: ptr.String()}
+		mset := prog.MethodSets.MethodSet(ptr)
+		for i := 0; i < mset.Len(); i++ {
+			sel := mset.At(i)
+			fn := prog.MethodValue(sel)
+			if fn == nil {
+				continue
+			}
+			methods.Methods = append(methods.Methods, MethodSource{
+				MethodSignature: MethodSignature{sel.Obj().Name(), signatureKey(sel.Obj().Type().(*types.Signature))},
+				Function:        functionInfo(declaredFunction(fn)),
+			})
+		}
+		if len(methods.Methods) > 0 {
+			summary.Types = append(summary.Types, methods)
+		}
+	}
+	return summary
+}
+
+// summarizeCall records a call site as a static edge or, for calls through
+// interfaces and function values, as an unresolved call.
+func summarizeCall(summary *PackageSummary, scope *Scope, fn *ssa.Function, caller FunctionInfo, site ssa.CallInstruction) {
+	common := site.Common()
+	if callee := common.StaticCallee(); callee != nil {
+		calleeInfo := functionInfo(declaredFunction(callee))
+		edge := CallEdge{
+			Caller: caller,
+			Callee: calleeInfo,
+			Scope:  scope.EdgeScope(caller.Package, calleeInfo.Package),
+		}
+		setSite(&edge, fn, site)
+		summary.Edges = append(summary.Edges, edge)
+		return
+	}
+	if _, ok := common.Value.(*ssa.Builtin); ok {
+		return
+	}
+
+	var edge CallEdge
+	setSite(&edge, fn, site)
+	call := UnresolvedCall{
+		Caller:       caller,
+		File:         edge.File,
+		Line:         edge.Line,
+		Column:       edge.Column,
+		Kind:         edge.Kind,
+		Mode:         edge.Mode,
+		ReceiverType: edge.ReceiverType,
+	}
+	if common.IsInvoke() {
+		call.Method = common.Method.Name()
+		mset := types.NewMethodSet(common.Value.Type())
+		for i := 0; i < mset.Len(); i++ {
+			obj := mset.At(i).Obj()
+			call.Interface = append(call.Interface, MethodSignature{obj.Name(), signatureKey(obj.Type().(*types.Signature))})
+		}
+	} else if sig, ok := common.Value.Type().Underlying().(*types.Signature); ok {
+		call.Signature = signatureKey(sig)
+	}
+	summary.Unresolved = append(summary.Unresolved, call)
+}
+
+// functionOperands returns the functions an instruction uses as values
+// rather than calling them directly, such as closures and method values.
+func functionOperands(instr ssa.Instruction) []*ssa.Function {
+	var callee ssa.Value
+	if site, ok := instr.(ssa.CallInstruction); ok && !site.Common().IsInvoke() {
+		callee = site.Common().Value
+	}
+	var fns []*ssa.Function
+	for _, operand := range instr.Operands(nil) {
+		if fn, ok := (*operand).(*ssa.Function); ok && *operand != callee {
+			fns = append(fns, fn)
+		}
+	}
+	return fns
+}
+
+// declaredFunction returns the method a synthetic wrapper, such as the
+// wrapper of a promoted method or a bound method value, stands for.
+// Generic instances are kept.
+func declaredFunction(fn *ssa.Function) *ssa.Function {
+	if fn.Synthetic == "" || fn.Origin() != nil || fn.Prog == nil {
+		return fn
+	}
+	if obj, ok := fn.Object().(*types.Func); ok {
+		if declared := fn.Prog.FuncValue(obj); declared != nil {
+			return declared
+		}
+	}
+	return fn
+}
+
+// signatureKey formats a signature without its receiver, so that methods
+// and function values with the same parameters and results compare equal.
+func signatureKey(sig *types.Signature) string {
+	return types.TypeString(types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic()), nil)
+}
+
+// SummaryLinkAlgorithm is the CallGraphResult.Algorithm of the call graphs
+// LinkSummaries links, which resolve dynamic calls like CHA but only among
+// the summarized packages.
+const SummaryLinkAlgorithm = "SUMMARY-LINK"
+
+// LinkSummaries links the summaries of the packages of a binary into its
+// call graph. The static edges of the summaries are kept; interface calls
+// are resolved, as by Class Hierarchy Analysis, to the methods of the
+// summarized types having every method of the interface, and calls through
+// function values to the summarized functions used as values with the same
+// signature. Types and functions of packages without a summary, such as
+// the standard library, are not candidates.
+//
+// The root is the package ID or import path of the binary's main package,
+// the first summary when empty. Packages whose ID is a workspace label are
+// internal. The diagnostics of the summaries, which may share the problems
+// of common dependencies, are carried over to the result once each.
+func LinkSummaries(summaries []*PackageSummary, root string) (*CallGraphResult, error) {
+	if len(summaries) == 0 {
+		return nil, fmt.Errorf("no summaries to link")
+	}
+	var rootSummary *PackageSummary
+	for _, s := range summaries {
+		if root != "" && (s.PackageID == root || s.ImportPath == root) {
+			rootSummary = s
+			break
+		}
+	}
+	var diagnostics []Diagnostic
+	if rootSummary == nil {
+		rootSummary = summaries[0]
+		if root != "" {
+			diagnostics = append(diagnostics, Diagnostic{Severity: SeverityWarning, Kind: DiagnosticFallback, Package: root,
+				Message: fmt.Sprintf("no summary of the root package %s, using %s instead", root, rootSummary.PackageID)})
+		}
+	}
+	seen := make(map[Diagnostic]bool)
+	for _, s := range summaries {
+		for _, d := range s.Diagnostics {
+			if !seen[d] {
+				seen[d] = true
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+
+	result := NewResult(&PackageJSON{ID: rootSummary.PackageID, Name: rootSummary.PackageName, PkgPath: rootSummary.ImportPath})
+	result.Algorithm = SummaryLinkAlgorithm
+	result.Diagnostics = diagnostics
+	scope := &Scope{RootPath: rootSummary.ImportPath, internal: make(map[string]bool)}
+	for _, s := range summaries {
+		if IsSourcePackage(s.PackageID) {
+			scope.internal[s.ImportPath] = true
+		}
+	}
+
+	// Index the candidate callees once, deduplicating the function values
+	// several packages take
+	methods := make(map[string]map[string]MethodSource)
+	var typeNames []string
+	values := make(map[string][]FunctionInfo)
+	seenValues := make(map[string]bool)
+	for _, s := range summaries {
+		for _, t := range s.Types {
+			if methods[t.Type] == nil {
+				methods[t.Type] = make(map[string]MethodSource)
+				typeNames = append(typeNames, t.Type)
+			}
+			for _, m := range t.Methods {
+				methods[t.Type][m.Name] = m
+			}
+		}
+		for _, v := range s.AddressTaken {
+			if key := v.Signature + " " + v.Function.ID; !seenValues[key] {
+				seenValues[key] = true
+				values[v.Signature] = append(values[v.Signature], v.Function)
+			}
+		}
+	}
+	sort.Strings(typeNames)
+
+	implementers := make(map[string][]string)
+	implementations := func(iface []MethodSignature) []string {
+		var key []string
+		for _, m := range iface {
+			key = append(key, m.Name+m.Signature)
+		}
+		k := strings.Join(key, ";")
+		if matches, ok := implementers[k]; ok {
+			return matches
+		}
+		var matches []string
+	next:
+		for _, name := range typeNames {
+			for _, m := range iface {
+				if impl, ok := methods[name][m.Name]; !ok || impl.Signature != m.Signature {
+					continue next
+				}
+			}
+			matches = append(matches, name)
+		}
+		implementers[k] = matches
+		return matches
+	}
+
+	addEdge := func(edge CallEdge) {
+		edge.Scope = scope.EdgeScope(edge.Caller.Package, edge.Callee.Package)
+		result.Functions[edge.Caller.ID] = edge.Caller
+		result.Functions[edge.Callee.ID] = edge.Callee
+		result.CallGraph[edge.Caller.ID] = append(result.CallGraph[edge.Caller.ID], edge.Callee.ID)
+		result.CallEdges = append(result.CallEdges, normalizeEdge(edge))
+	}
+
+	for _, s := range summaries {
+		for id, info := range s.Functions {
+			result.Functions[id] = info
+		}
+		for _, edge := range s.Edges {
+			addEdge(edge)
+		}
+		for _, call := range s.Unresolved {
+			var callees []FunctionInfo
+			if call.Kind == CallInterface {
+				for _, t := range implementations(call.Interface) {
+					callees = append(callees, methods[t][call.Method].Function)
+				}
+			} else {
+				callees = values[call.Signature]
+			}
+			for _, callee := range callees {
+				addEdge(CallEdge{
+					Caller:       call.Caller,
+					Callee:       callee,
+					File:         call.File,
+					Line:         call.Line,
+					Column:       call.Column,
+					Kind:         call.Kind,
+					Mode:         call.Mode,
+					ReceiverType: call.ReceiverType,
+				})
+			}
+		}
+	}
+
+	result.TotalFuncs = len(result.CallGraph)
+	result.TotalEdges = len(result.CallEdges)
+	return result, nil
+}
+
+// ReadSummary reads a package summary written by WriteSummary.
+func ReadSummary(path string) (*PackageSummary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read summary file: %v", err)
+	}
+
+	var summary PackageSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse summary file %s: %v", path, err)
+	}
+	return &summary, nil
+}
+
+// WriteSummary validates the summary against the callgraph_summary schema
+// and writes it as indented JSON, creating the output directory if needed.
+func WriteSummary(outputFile string, summary *PackageSummary) error {
+	return writeJSON(outputFile, summary, schema.CallGraphSummary)
+}
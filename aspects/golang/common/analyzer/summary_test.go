@@ -0,0 +1,205 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+	"golang.org/x/tools/go/packages"
+)
+
+// workspaceRoot is the repository root holding the example packages of
+// src/, relative to this package.
+var workspaceRoot = filepath.Join("..", "..", "..", "..")
+
+// loadSources loads the example packages under src/. Their dependencies are
+// left without syntax, so they have no function bodies, as with the
+// exportdata loader.
+func loadSources(t *testing.T) []*packages.Package {
+	t.Helper()
+	if _, err := os.Stat(filepath.Join(workspaceRoot, "go.mod")); err != nil {
+		t.Skip("the workspace holding src/ is not available")
+	}
+	return loadPackages(t, workspaceRoot, "./src/...")
+}
+
+// loadPackages loads the packages matching the patterns in dir, leaving the
+// packages they import from elsewhere without syntax.
+func loadPackages(t *testing.T, dir string, patterns ...string) []*packages.Package {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command to load packages with")
+	}
+
+	cfg := &packages.Config{
+		Mode: loadMode,
+		Dir:  dir,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", dir, err)
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		t.Fatalf("%s has errors", dir)
+	}
+	roots := make(map[*packages.Package]bool)
+	for _, pkg := range pkgs {
+		roots[pkg] = true
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if !roots[pkg] {
+			pkg.Syntax, pkg.TypesInfo = nil, nil
+		}
+	})
+	return pkgs
+}
+
+// siteKey identifies an edge by its caller, callee and call site.
+func siteKey(edge NormalizedEdge) string {
+	return fmt.Sprintf("%s -> %s at %s:%d:%d", edge.Caller, edge.Callee, edge.File, edge.Line, edge.Column)
+}
+
+// edgeSet returns the keys of the edges of the result whose caller belongs
+// to one of the packages and whose kind is one of the kinds.
+func edgeSet(result *CallGraphResult, edges []NormalizedEdge, pkgs map[string]bool, kinds ...string) map[string]bool {
+	set := make(map[string]bool)
+	for _, edge := range edges {
+		for _, kind := range kinds {
+			if edge.Kind == kind && pkgs[result.Functions[edge.Caller].Package] {
+				set[siteKey(edge)] = true
+			}
+		}
+	}
+	return set
+}
+
+// missing returns the sorted keys of want that are not in got.
+func missing(want, got map[string]bool) []string {
+	var keys []string
+	for key := range want {
+		if !got[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestLinkSummariesMatchesWholeProgram(t *testing.T) {
+	pkgs := loadSources(t)
+	prog, initial, failed, err := BuildProgram(pkgs, AlgorithmStatic.builderMode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) > 0 {
+		t.Fatalf("SSA construction failed for %d packages", len(failed))
+	}
+
+	scope := &Scope{All: true, internal: make(map[string]bool)}
+	sources := make(map[string]bool)
+	var summaries []*PackageSummary
+	var mainPath string
+	for _, pkg := range initial {
+		path := pkg.Pkg.Path()
+		sources[path] = true
+		scope.internal[path] = true
+		if pkg.Pkg.Name() == "main" {
+			mainPath = path
+		}
+	}
+	for _, pkg := range initial {
+		summary := SummarizePackage(prog, pkg, scope)
+		summary.PackageID = "//" + strings.TrimPrefix(pkg.Pkg.Path(), "github.com/example/go-aspects/")
+		summaries = append(summaries, summary)
+	}
+	if mainPath == "" {
+		t.Fatal("no main package in src/")
+	}
+
+	linked, err := LinkSummaries(summaries, mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.Algorithm != SummaryLinkAlgorithm {
+		t.Errorf("linked algorithm = %q, want %q", linked.Algorithm, SummaryLinkAlgorithm)
+	}
+
+	// The static algorithm leaves out closures only called dynamically, so
+	// compare with CHA, which keeps every function
+	cg, err := BuildCallGraph(prog, AlgorithmCHA, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cha := NewResult(nil)
+	Extract(cg, cha, scope)
+
+	// Static calls are summarized exactly
+	static := edgeSet(cha, cha.CallEdges, sources, CallStatic)
+	linkedStatic := edgeSet(linked, linked.CallEdges, sources, CallStatic)
+	if len(static) == 0 {
+		t.Fatal("no static edges in src/")
+	}
+	for _, key := range missing(static, linkedStatic) {
+		t.Errorf("linked call graph misses static edge %s", key)
+	}
+	for _, key := range missing(linkedStatic, static) {
+		t.Errorf("linked call graph has extra static edge %s", key)
+	}
+
+	// Dynamic calls are resolved like CHA, among the summarized functions
+	chaDynamic := edgeSet(cha, cha.CallEdges, sources, CallInterface, CallDynamic)
+	linkedDynamic := edgeSet(linked, linked.CallEdges, sources, CallInterface, CallDynamic)
+	for _, key := range missing(linkedDynamic, chaDynamic) {
+		t.Errorf("linked call graph has edge %s that CHA does not", key)
+	}
+	var chaInterface []NormalizedEdge
+	for _, edge := range cha.CallEdges {
+		if edge.Kind == CallInterface && sources[cha.Functions[edge.Callee].Package] {
+			chaInterface = append(chaInterface, edge)
+		}
+	}
+	for _, key := range missing(edgeSet(cha, chaInterface, sources, CallInterface), linkedDynamic) {
+		t.Errorf("linked call graph misses interface edge %s", key)
+	}
+}
+
+func TestWithoutGoFiles(t *testing.T) {
+	response := &PackagesResponse{
+		Roots:    []string{"//lib:asm"},
+		Packages: []*PackageJSON{{ID: "//lib:asm", Name: "asm", PkgPath: "example.com/lib/asm"}},
+	}
+
+	result, err := Analyze(&Config{Loader: LoaderExportData}, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.ValidateValue(schema.CallGraphResult, result); err != nil {
+		t.Fatal(err)
+	}
+	if result.PackageID != "//lib:asm" || result.TotalFuncs != 0 || len(result.Diagnostics) != 0 {
+		t.Errorf("result of a package without Go files = %+v, want an empty result without diagnostics", result)
+	}
+
+	summary, err := Summarize(&Config{Loader: LoaderExportData}, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.ValidateValue(schema.CallGraphSummary, summary); err != nil {
+		t.Fatal(err)
+	}
+	linked, err := LinkSummaries([]*PackageSummary{summary}, summary.PackageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.ValidateValue(schema.CallGraphResult, linked); err != nil {
+		t.Fatal(err)
+	}
+	if linked.ImportPath != "example.com/lib/asm" || linked.TotalEdges != 0 {
+		t.Errorf("linked result of a package without Go files = %+v, want an empty result", linked)
+	}
+}
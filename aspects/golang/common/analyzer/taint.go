@@ -0,0 +1,525 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// DefaultTaintSources are the functions whose results taint mode treats as
+// user input by default, named as package path, receiver type and method.
+var DefaultTaintSources = []string{
+	"net/http.Request.FormValue",
+	"net/http.Request.PostFormValue",
+	"net/url.URL.Query",
+	"github.com/gorilla/mux.Vars",
+	"github.com/gin-gonic/gin.Context.Param",
+	"github.com/gin-gonic/gin.Context.Query",
+	"github.com/gin-gonic/gin.Context.PostForm",
+}
+
+// DefaultTaintSinks are the functions taint mode reports user input
+// reaching by default. A type in parentheses restricts a sink to calls whose
+// first argument has that type, so fmt.Fprint and io.WriteString are only
+// sinks when they write to an http.ResponseWriter.
+var DefaultTaintSinks = []string{
+	"database/sql.DB.Query",
+	"database/sql.DB.QueryContext",
+	"database/sql.DB.QueryRow",
+	"database/sql.DB.QueryRowContext",
+	"database/sql.DB.Exec",
+	"database/sql.DB.ExecContext",
+	"fmt.Fprint(net/http.ResponseWriter)",
+	"fmt.Fprintf(net/http.ResponseWriter)",
+	"fmt.Fprintln(net/http.ResponseWriter)",
+	"io.WriteString(net/http.ResponseWriter)",
+	"net/http.ResponseWriter.Write",
+	"os/exec.Command",
+	"os/exec.CommandContext",
+}
+
+// TaintFlow is a data flow from the result of a source call to an argument
+// of a sink call.
+type TaintFlow struct {
+	Source string `json:"source"`
+	Sink   string `json:"sink"`
+	// Function is the workspace function the flow starts in
+	Function string `json:"function"`
+	// Path lists the calls the data passes through, from the source call
+	// to the sink call
+	Path []TaintStep `json:"path"`
+}
+
+// TaintStep is a call on a taint flow path.
+type TaintStep struct {
+	// Function makes the call to Call
+	Function string `json:"function"`
+	Call     string `json:"call"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// taintSink is a sink function, optionally restricted to calls writing to a
+// given type.
+type taintSink struct {
+	// writerSpec names the type of the first argument, empty for none;
+	// writer is nil when that type is not part of the program
+	writerSpec string
+	writer     types.Type
+}
+
+// taintTrace is the path tainted data took to reach a value.
+type taintTrace struct {
+	source string
+	steps  []TaintStep
+}
+
+// then returns the trace extended with steps, sharing no storage with t.
+func (t taintTrace) then(steps ...TaintStep) taintTrace {
+	extended := make([]TaintStep, 0, len(t.steps)+len(steps))
+	extended = append(append(extended, t.steps...), steps...)
+	return taintTrace{source: t.source, steps: extended}
+}
+
+// taintHit is tainted data reaching a sink.
+type taintHit struct {
+	sink  string
+	trace taintTrace
+}
+
+// taintSummary is where data tainted at a value of a function flows: to the
+// function's results and to sinks, including sinks of the functions it
+// calls.
+type taintSummary struct {
+	returns *taintTrace
+	sinks   []taintHit
+}
+
+// taintSeed is a value tainted at the start of a propagation.
+type taintSeed struct {
+	value ssa.Value
+	trace taintTrace
+}
+
+type taintParam struct {
+	fn    *ssa.Function
+	index int
+}
+
+type taintAnalysis struct {
+	prog     *ssa.Program
+	sources  map[string]bool
+	sinks    map[string][]taintSink
+	internal func(pkgPath string) bool
+	callees  map[ssa.CallInstruction][]*ssa.Function
+	params   map[taintParam]*taintSummary
+	returned map[*ssa.Function][]taintTrace
+}
+
+// TaintFlows reports the flows of data from the results of source calls to
+// the arguments of sink calls in the workspace functions of the call graph
+// kept by the scope. Sources and sinks are named as in DefaultTaintSources
+// and DefaultTaintSinks.
+//
+// Within a function, taint propagates through SSA values: operations,
+// conversions, phis, stores and loads of the same variable, struct field or
+// slice element, map updates and channel sends. Calls of workspace functions,
+// including calls resolved through the call graph, are followed through
+// summaries of where each parameter flows; the result of any other call is
+// tainted when one of its arguments or its receiver is. Values captured by
+// closures and stored in globals are not followed.
+func TaintFlows(prog *ssa.Program, cg *callgraph.Graph, scope *Scope, sources, sinks []string) []TaintFlow {
+	a := &taintAnalysis{
+		prog:     prog,
+		sources:  make(map[string]bool),
+		sinks:    make(map[string][]taintSink),
+		internal: scope.IsInternal,
+		callees:  make(map[ssa.CallInstruction][]*ssa.Function),
+		params:   make(map[taintParam]*taintSummary),
+		returned: make(map[*ssa.Function][]taintTrace),
+	}
+	for _, spec := range sources {
+		a.sources[spec] = true
+	}
+	for _, spec := range sinks {
+		name, writer, _ := strings.Cut(spec, "(")
+		sink := taintSink{writerSpec: strings.TrimSuffix(writer, ")")}
+		if sink.writerSpec != "" {
+			sink.writer = lookupType(prog, sink.writerSpec)
+		}
+		a.sinks[name] = append(a.sinks[name], sink)
+	}
+
+	var fns []*ssa.Function
+	for fn, node := range cg.Nodes {
+		if fn == nil {
+			continue
+		}
+		for _, edge := range node.Out {
+			if edge.Site != nil && edge.Callee != nil && edge.Callee.Func != nil {
+				a.callees[edge.Site] = append(a.callees[edge.Site], edge.Callee.Func)
+			}
+		}
+		if pkgPath := functionPackagePath(fn); a.analyzed(fn) && scope.Keeps(pkgPath) {
+			fns = append(fns, fn)
+		}
+	}
+	sort.Slice(fns, func(i, j int) bool {
+		return FunctionID(fns[i]) < FunctionID(fns[j])
+	})
+
+	flows := []TaintFlow{}
+	seen := make(map[string]bool)
+	for _, fn := range fns {
+		for _, seed := range a.seeds(fn) {
+			summary := a.propagate(fn, seed)
+			for _, hit := range summary.sinks {
+				key := taintPathKey(hit.trace.steps)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				flows = append(flows, TaintFlow{
+					Source:   hit.trace.source,
+					Sink:     hit.sink,
+					Function: FunctionID(fn),
+					Path:     hit.trace.steps,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(flows, func(i, j int) bool {
+		a, b := flows[i].Path[0], flows[j].Path[0]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return flows
+}
+
+// analyzed reports whether taint is followed into fn: a workspace function
+// with a body.
+func (a *taintAnalysis) analyzed(fn *ssa.Function) bool {
+	return fn.Blocks != nil && fn.Synthetic == "" && a.internal(functionPackagePath(fn))
+}
+
+// seeds returns the tainted values a function starts with: the results of
+// its source calls and of its calls of workspace functions returning data
+// read from a source.
+func (a *taintAnalysis) seeds(fn *ssa.Function) []taintSeed {
+	var seeds []taintSeed
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			if name := calleeName(call.Common()); a.sources[name] {
+				seeds = append(seeds, taintSeed{call, taintTrace{source: name}.then(a.step(fn, call, name))})
+				continue
+			}
+			for _, target := range a.targets(call) {
+				for _, trace := range a.sourcedReturns(target) {
+					step := a.step(fn, call, FunctionID(target))
+					seeds = append(seeds, taintSeed{call, taintTrace{source: trace.source}.then(step).then(trace.steps...)})
+				}
+			}
+		}
+	}
+	return seeds
+}
+
+// sourcedReturns returns the traces of the source data a function returns.
+// Recursive calls see no sourced results.
+func (a *taintAnalysis) sourcedReturns(fn *ssa.Function) []taintTrace {
+	if traces, ok := a.returned[fn]; ok {
+		return traces
+	}
+	a.returned[fn] = nil
+	var traces []taintTrace
+	for _, seed := range a.seeds(fn) {
+		if summary := a.propagate(fn, seed); summary.returns != nil {
+			traces = append(traces, *summary.returns)
+		}
+	}
+	a.returned[fn] = traces
+	return traces
+}
+
+// paramSummary returns where data passed as the parameter of a function
+// flows. Recursive calls see an empty summary.
+func (a *taintAnalysis) paramSummary(fn *ssa.Function, index int) *taintSummary {
+	key := taintParam{fn, index}
+	if summary, ok := a.params[key]; ok {
+		return summary
+	}
+	a.params[key] = &taintSummary{}
+	summary := a.propagate(fn, taintSeed{value: fn.Params[index]})
+	a.params[key] = &summary
+	return &summary
+}
+
+// propagate follows the tainted seed through the values of the function and
+// returns where it flows. Every value keeps the first, and so shortest,
+// trace reaching it.
+func (a *taintAnalysis) propagate(fn *ssa.Function, seed taintSeed) taintSummary {
+	var summary taintSummary
+	tainted := map[ssa.Value]taintTrace{seed.value: seed.trace}
+	queue := []ssa.Value{seed.value}
+	mark := func(v ssa.Value, trace taintTrace) {
+		if _, ok := tainted[v]; !ok {
+			tainted[v] = trace
+			queue = append(queue, v)
+		}
+	}
+
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		trace := tainted[v]
+		refs := v.Referrers()
+		if refs == nil {
+			continue
+		}
+		for _, instr := range *refs {
+			switch instr := instr.(type) {
+			case ssa.CallInstruction:
+				a.call(fn, instr, v, trace, mark, &summary)
+			case *ssa.Store:
+				if instr.Val != v {
+					continue
+				}
+				// Storing into a field or element taints the whole
+				// variable, so loads of other parts see it too
+				addr := instr.Addr
+				for addr != nil {
+					mark(addr, trace)
+					switch x := addr.(type) {
+					case *ssa.FieldAddr:
+						addr = x.X
+					case *ssa.IndexAddr:
+						addr = x.X
+					default:
+						addr = nil
+					}
+				}
+			case *ssa.MapUpdate:
+				if instr.Key == v || instr.Value == v {
+					mark(instr.Map, trace)
+				}
+			case *ssa.Send:
+				if instr.X == v {
+					mark(instr.Chan, trace)
+				}
+			case *ssa.Return:
+				if summary.returns == nil {
+					returns := trace.then()
+					summary.returns = &returns
+				}
+			case *ssa.MakeClosure:
+				// Captured values are not followed
+			case ssa.Value:
+				mark(instr, trace)
+			}
+		}
+	}
+	return summary
+}
+
+// call propagates the tainted value v through a call using it as an
+// argument or receiver: into a sink, into the summaries of the workspace
+// functions called, or to the result of any other call.
+func (a *taintAnalysis) call(fn *ssa.Function, instr ssa.CallInstruction, v ssa.Value, trace taintTrace, mark func(ssa.Value, taintTrace), summary *taintSummary) {
+	common := instr.Common()
+	args := callArgs(common)
+	var indices []int
+	for i, arg := range args {
+		if arg == v {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) == 0 {
+		return
+	}
+
+	name := calleeName(common)
+	if sinks, ok := a.sinks[name]; ok {
+		for _, sink := range sinks {
+			if sink.reached(common, args, indices) {
+				summary.sinks = append(summary.sinks, taintHit{name, trace.then(a.step(fn, instr, name))})
+				break
+			}
+		}
+		return
+	}
+
+	targets := a.targets(instr)
+	if len(targets) == 0 {
+		if call, ok := instr.(*ssa.Call); ok {
+			mark(call, trace.then(a.step(fn, instr, name)))
+		}
+		return
+	}
+	for _, target := range targets {
+		step := a.step(fn, instr, FunctionID(target))
+		for _, i := range indices {
+			if i >= len(target.Params) {
+				continue
+			}
+			callee := a.paramSummary(target, i)
+			if call, ok := instr.(*ssa.Call); ok && callee.returns != nil {
+				mark(call, trace.then(step).then(callee.returns.steps...))
+			}
+			for _, hit := range callee.sinks {
+				summary.sinks = append(summary.sinks, taintHit{hit.sink, trace.then(step).then(hit.trace.steps...)})
+			}
+		}
+	}
+}
+
+// targets returns the workspace functions a call may invoke.
+func (a *taintAnalysis) targets(instr ssa.CallInstruction) []*ssa.Function {
+	candidates := a.callees[instr]
+	if callee := instr.Common().StaticCallee(); callee != nil {
+		candidates = []*ssa.Function{callee}
+	}
+	var targets []*ssa.Function
+	for _, fn := range candidates {
+		if a.analyzed(fn) {
+			targets = append(targets, fn)
+		}
+	}
+	return targets
+}
+
+// step returns the flow step of a call made by fn.
+func (a *taintAnalysis) step(fn *ssa.Function, instr ssa.CallInstruction, call string) TaintStep {
+	step := TaintStep{Function: FunctionID(fn), Call: call}
+	if pos := instr.Pos(); pos.IsValid() {
+		position := a.prog.Fset.Position(pos)
+		step.File, step.Line, step.Column = position.Filename, position.Line, position.Column
+	}
+	return step
+}
+
+// reached reports whether the tainted arguments at indices reach the sink:
+// any argument except the receiver and, for sinks restricted to a writer
+// type, the writer, which must have that type.
+func (s taintSink) reached(common *ssa.CallCommon, args []ssa.Value, indices []int) bool {
+	first := 0
+	if callee := common.StaticCallee(); common.IsInvoke() || (callee != nil && callee.Signature.Recv() != nil) {
+		first++
+	}
+	if s.writerSpec != "" {
+		if first >= len(args) || !s.writes(args[first]) {
+			return false
+		}
+		first++
+	}
+	for _, i := range indices {
+		if i >= first {
+			return true
+		}
+	}
+	return false
+}
+
+// writes reports whether the value, before its conversion to an interface,
+// has the writer type of the sink or implements it.
+func (s taintSink) writes(v ssa.Value) bool {
+	if s.writer == nil {
+		return false
+	}
+	for {
+		switch x := v.(type) {
+		case *ssa.MakeInterface:
+			v = x.X
+			continue
+		case *ssa.ChangeInterface:
+			v = x.X
+			continue
+		}
+		break
+	}
+	if types.Identical(v.Type(), s.writer) {
+		return true
+	}
+	iface, ok := s.writer.Underlying().(*types.Interface)
+	return ok && types.Implements(v.Type(), iface)
+}
+
+// callArgs returns the arguments of a call with the receiver of an
+// interface method call first, so they line up with the parameters of the
+// called function.
+func callArgs(common *ssa.CallCommon) []ssa.Value {
+	if common.IsInvoke() {
+		return append([]ssa.Value{common.Value}, common.Args...)
+	}
+	return common.Args
+}
+
+// calleeName names the function a call invokes as in the source and sink
+// lists, e.g. "net/http.Request.FormValue" or "fmt.Fprint". Calls of
+// builtins and function values are named after the value.
+func calleeName(common *ssa.CallCommon) string {
+	var obj *types.Func
+	if common.IsInvoke() {
+		obj = common.Method
+	} else if callee := common.StaticCallee(); callee != nil {
+		if callee.Origin() != nil {
+			callee = callee.Origin()
+		}
+		if obj, _ = callee.Object().(*types.Func); obj == nil {
+			return FunctionID(callee)
+		}
+	} else {
+		return common.Value.Name()
+	}
+
+	if obj.Pkg() == nil {
+		return obj.Name()
+	}
+	name := obj.Pkg().Path() + "."
+	if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+		if named := namedReceiver(recv.Type()); named != nil {
+			name += named.Obj().Name() + "."
+		}
+	}
+	return name + obj.Name()
+}
+
+// lookupType returns the named type of a package path and type name such as
+// "net/http.ResponseWriter", or nil when the program lacks it.
+func lookupType(prog *ssa.Program, spec string) types.Type {
+	i := strings.LastIndex(spec, ".")
+	if i < 0 {
+		return nil
+	}
+	pkg := prog.ImportedPackage(spec[:i])
+	if pkg == nil {
+		return nil
+	}
+	if member, ok := pkg.Members[spec[i+1:]].(*ssa.Type); ok {
+		return member.Type()
+	}
+	return nil
+}
+
+// taintPathKey identifies a flow by its calls and their positions.
+func taintPathKey(steps []TaintStep) string {
+	var parts []string
+	for _, step := range steps {
+		parts = append(parts, fmt.Sprintf("%s@%s:%d:%d", step.Call, step.File, step.Line, step.Column))
+	}
+	return strings.Join(parts, "|")
+}
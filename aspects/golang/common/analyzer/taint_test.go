@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// flowString renders a flow as "source -> sink: call@line, ...", listing
+// the calls of its path.
+func flowString(flow TaintFlow) string {
+	var calls []string
+	for _, step := range flow.Path {
+		calls = append(calls, fmt.Sprintf("%s@%d", step.Call, step.Line))
+	}
+	return fmt.Sprintf("%s -> %s: %s", flow.Source, flow.Sink, strings.Join(calls, ", "))
+}
+
+func TestTaintFlows(t *testing.T) {
+	tests := []struct {
+		// pkg is the package below example.com holding the handler, whose
+		// body starts on line 5
+		pkg     string
+		imports string
+		body    string
+		decls   string
+		sources []string
+		sinks   []string
+		want    []string
+	}{
+		{
+			pkg:     "direct",
+			imports: `"net/http"; "os/exec"`,
+			body: `cmd := r.FormValue("cmd")
+	exec.Command(cmd)`,
+			want: []string{"net/http.Request.FormValue -> os/exec.Command: net/http.Request.FormValue@5, os/exec.Command@6"},
+		},
+		{
+			pkg:     "helper",
+			imports: `"database/sql"; "net/http"`,
+			body: `name := r.FormValue("name")
+	lookup(name + "%")`,
+			decls: `var db *sql.DB
+
+func lookup(name string) { db.Query("SELECT * FROM users WHERE name LIKE " + name) }`,
+			want: []string{"net/http.Request.FormValue -> database/sql.DB.Query: net/http.Request.FormValue@5, example.com/helper.lookup@6, database/sql.DB.Query@11"},
+		},
+		{
+			pkg:     "returned",
+			imports: `"net/http"; "os/exec"`,
+			body:    `exec.Command("sh", "-c", param(r))`,
+			decls:   `func param(r *http.Request) string { return r.URL.Query().Get("q") }`,
+			want:    []string{"net/url.URL.Query -> os/exec.Command: example.com/returned.param@5, net/url.URL.Query@8, net/url.Values.Get@8, os/exec.Command@5"},
+		},
+		{
+			pkg:     "untainted",
+			imports: `"net/http"; "os/exec"`,
+			body: `_ = r.FormValue("cmd")
+	exec.Command("ls")`,
+		},
+		{
+			// Printing user input is only a sink when it is written to the
+			// response
+			pkg:     "writer",
+			imports: `"fmt"; "net/http"; "os"`,
+			body: `msg := r.FormValue("msg")
+	fmt.Fprintf(os.Stderr, "%s", msg)
+	fmt.Fprintf(w, "%s", msg)`,
+			want: []string{"net/http.Request.FormValue -> fmt.Fprintf: net/http.Request.FormValue@5, fmt.Fprintf@7"},
+		},
+		{
+			pkg:     "response",
+			imports: `"net/http"`,
+			body:    `w.Write([]byte(r.FormValue("msg")))`,
+			want:    []string{"net/http.Request.FormValue -> net/http.ResponseWriter.Write: net/http.Request.FormValue@5, net/http.ResponseWriter.Write@5"},
+		},
+		{
+			pkg:     "writestring",
+			imports: `"io"; "net/http"; "os"`,
+			body: `msg := r.FormValue("msg")
+	io.WriteString(os.Stdout, msg)
+	io.WriteString(w, msg)`,
+			want: []string{"net/http.Request.FormValue -> io.WriteString: net/http.Request.FormValue@5, io.WriteString@7"},
+		},
+		{
+			pkg:     "custom",
+			imports: `"net/http"; "os"`,
+			body:    `os.Remove(r.Header.Get("X-File"))`,
+			sources: []string{"net/http.Header.Get"},
+			sinks:   []string{"os.Remove"},
+			want:    []string{"net/http.Header.Get -> os.Remove: net/http.Header.Get@5, os.Remove@5"},
+		},
+	}
+
+	files := make(map[string]string)
+	for _, tt := range tests {
+		files[tt.pkg+"/handler.go"] = "package " + tt.pkg + "\n\nimport (" + tt.imports + ")\n" +
+			"func Handle(w http.ResponseWriter, r *http.Request) {\n\t" + tt.body + "\n}\n\n" + tt.decls + "\n"
+	}
+	prog, _, cg := testProgram(t, files)
+
+	for _, tt := range tests {
+		t.Run(tt.pkg, func(t *testing.T) {
+			sources, sinks := tt.sources, tt.sinks
+			if sources == nil {
+				sources = DefaultTaintSources
+			}
+			if sinks == nil {
+				sinks = DefaultTaintSinks
+			}
+			scope := &Scope{internal: map[string]bool{"example.com/" + tt.pkg: true}}
+			var got []string
+			for _, flow := range TaintFlows(prog, cg, scope, sources, sinks) {
+				if want := "example.com/" + tt.pkg + ".Handle"; flow.Function != want {
+					t.Errorf("flow %s starts in %s, want %s", flowString(flow), flow.Function, want)
+				}
+				got = append(got, flowString(flow))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TaintFlows =\n  %q\nwant\n  %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package analyzer
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Test coverage labels recorded on functions in test mode.
+const (
+	// CoverageCovered marks a function with a static call path from a test.
+	CoverageCovered = "covered"
+	// CoverageUncovered marks a function no test reaches.
+	CoverageUncovered = "uncovered"
+)
+
+// testFunctionPrefixes maps the name prefixes of the functions go test runs
+// to the type of their parameter.
+var testFunctionPrefixes = map[string]string{
+	"Test":      "*testing.T",
+	"Benchmark": "*testing.B",
+	"Fuzz":      "*testing.F",
+}
+
+// TestEntrypoints returns the Test, Benchmark and Fuzz functions declared in
+// the _test.go files of the packages, ordered by ID.
+func TestEntrypoints(pkgs []*ssa.Package) []*ssa.Function {
+	var roots []*ssa.Function
+	for _, pkg := range pkgs {
+		if pkg == nil {
+			continue
+		}
+		for _, member := range pkg.Members {
+			if fn, ok := member.(*ssa.Function); ok && isTestFunction(fn) {
+				roots = append(roots, fn)
+			}
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return FunctionID(roots[i]) < FunctionID(roots[j])
+	})
+	return roots
+}
+
+// isTestFunction reports whether fn is a function go test runs: a function
+// of a _test.go file named like TestXxx with a single *testing.T parameter,
+// or the Benchmark and Fuzz equivalents.
+func isTestFunction(fn *ssa.Function) bool {
+	if !isTestFile(fn) {
+		return false
+	}
+	sig := fn.Signature
+	if sig.Recv() != nil || sig.Params().Len() != 1 || sig.Results().Len() != 0 {
+		return false
+	}
+	for prefix, param := range testFunctionPrefixes {
+		if !strings.HasPrefix(fn.Name(), prefix) {
+			continue
+		}
+		// As in go test, the character after the prefix must not be
+		// lower case, so Testing is not a test but Test and Test_x are
+		if rest := fn.Name()[len(prefix):]; rest != "" {
+			if r, _ := utf8.DecodeRuneInString(rest); unicode.IsLower(r) {
+				return false
+			}
+		}
+		return types.TypeString(sig.Params().At(0).Type(), nil) == param
+	}
+	return false
+}
+
+// isTestFile reports whether fn is declared in a _test.go file.
+func isTestFile(fn *ssa.Function) bool {
+	if fn.Prog == nil || !fn.Pos().IsValid() {
+		return false
+	}
+	return strings.HasSuffix(fn.Prog.Fset.Position(fn.Pos()).Filename, "_test.go")
+}
+
+// TestCoverage labels the functions of the result as covered when the walk
+// from the test roots reaches them and uncovered otherwise. It returns the
+// functions reachable from the roots that no test reaches, limited to the
+// workspace packages kept by the scope.
+//
+// Functions are matched by ID, since a package and its test variant are
+// loaded as distinct packages with the same functions.
+func TestCoverage(cg *callgraph.Graph, roots, testRoots []*ssa.Function, result *CallGraphResult, scope *Scope) []string {
+	covered := make(map[string]bool)
+	for _, fn := range walkCallGraph(cg, testRoots).order {
+		covered[FunctionID(fn)] = true
+	}
+
+	for id, info := range result.Functions {
+		info.Coverage = CoverageUncovered
+		if covered[id] {
+			info.Coverage = CoverageCovered
+		}
+		result.Functions[id] = info
+	}
+
+	untested := []string{}
+	seen := make(map[string]bool)
+	for _, fn := range walkCallGraph(cg, roots).order {
+		id := FunctionID(fn)
+		if covered[id] || seen[id] || fn.Synthetic != "" || isTestFile(fn) {
+			continue
+		}
+		if pkgPath := functionPackagePath(fn); !scope.IsInternal(pkgPath) || !scope.Keeps(pkgPath) {
+			continue
+		}
+		seen[id] = true
+		untested = append(untested, id)
+	}
+	sort.Strings(untested)
+	return untested
+}
+
+// withoutTestMains drops the generated main packages of test binaries,
+// whose IDs end in ".test", from packages loaded with tests.
+func withoutTestMains(pkgs []*packages.Package) []*packages.Package {
+	var kept []*packages.Package
+	for _, pkg := range pkgs {
+		if pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		kept = append(kept, pkg)
+	}
+	return kept
+}
@@ -0,0 +1,116 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/example/go-aspects/aspects/golang/common/cgbin"
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+)
+
+// ValidateFile validates an output file against its published schema and
+// returns the schema it was validated against. A sharded result directory
+// is validated shard by shard, NDJSON files record by record and binary
+// results after decoding. Other files are JSON documents validated against
+// schemaRef, or against the schema their top-level properties identify
+// when schemaRef is empty.
+func ValidateFile(path, schemaRef string) (string, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return schema.ShardManifest, validateShards(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	if cgbin.IsBinary(data) {
+		result, err := DecodeBinary(bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		return schema.CallGraphResult, schema.ValidateValue(schema.CallGraphResult, result)
+	}
+
+	var top map[string]json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&top); err != nil {
+		return "", fmt.Errorf("invalid JSON: %v", err)
+	}
+	if _, ok := top["type"]; ok && schemaRef == "" {
+		return schema.CallGraphRecord, validateRecords(bytes.NewReader(data))
+	}
+
+	if schemaRef == "" {
+		schemaRef = detectSchema(top)
+	}
+	return schemaRef, schema.Validate(schemaRef, data)
+}
+
+// detectSchema identifies the schema of a JSON document by its top-level
+// properties.
+func detectSchema(top map[string]json.RawMessage) string {
+	switch {
+	case top["nodes"] != nil:
+		return schema.Dependencies
+	case top["Packages"] != nil || top["Roots"] != nil:
+		return schema.Packages
+	case top["shards"] != nil:
+		return schema.ShardManifest
+	case top["unresolved"] != nil || top["address_taken"] != nil:
+		return schema.CallGraphSummary
+	case top["added_edges"] != nil || top["sensitive_paths"] != nil:
+		return schema.CallGraphDiff
+	default:
+		return schema.CallGraphResult
+	}
+}
+
+// validateRecords validates every record of an NDJSON stream.
+func validateRecords(r io.Reader) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for n := 1; ; n++ {
+		var record json.RawMessage
+		if err := dec.Decode(&record); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("record %d: invalid JSON: %v", n, err)
+		}
+		if err := schema.Validate(schema.CallGraphRecord, record); err != nil {
+			return fmt.Errorf("record %d: %v", n, err)
+		}
+	}
+}
+
+// validateShards validates the manifest and the shards of a sharded result.
+func validateShards(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, ShardManifestFile))
+	if err != nil {
+		return fmt.Errorf("failed to read shard manifest: %v", err)
+	}
+	if err := schema.Validate(schema.ShardManifest, data); err != nil {
+		return fmt.Errorf("%s: %v", ShardManifestFile, err)
+	}
+
+	var manifest ShardManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("failed to parse shard manifest %s: %v", dir, err)
+	}
+	for _, shard := range manifest.Shards {
+		file, err := os.Open(filepath.Join(dir, shard.File))
+		if err != nil {
+			return fmt.Errorf("failed to read shard: %v", err)
+		}
+		err = validateRecords(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", shard.File, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package analyzer
+
+import (
+	"go/types"
+	"sort"
+
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Vulnerability finding statuses, from least to most severe.
+const (
+	// VulnUnreachable means the module is a dependency at an affected
+	// version, but the vulnerable package is not imported.
+	VulnUnreachable = "unreachable"
+	// VulnImported means the vulnerable package is imported, but none of
+	// the affected symbols is reachable from the entrypoints.
+	VulnImported = "imported"
+	// VulnReachable means an affected symbol is reachable from the
+	// entrypoints.
+	VulnReachable = "reachable"
+)
+
+// VulnFinding is one advisory affecting one vulnerable package of a
+// dependency.
+type VulnFinding struct {
+	ID      string   `json:"id"`
+	Aliases []string `json:"aliases,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	Module  string   `json:"module"`
+	Version string   `json:"version"`
+	// VersionUnknown means the dependency version, such as the "external"
+	// placeholder of unresolved repositories, is not semver, so the
+	// advisory may not apply to it.
+	VersionUnknown bool   `json:"version_unknown,omitempty"`
+	Label          string `json:"label"`
+	Package        string `json:"package"`
+	// Symbols are the affected symbols listed by the advisory; empty means
+	// the whole package is affected.
+	Symbols          []string `json:"symbols,omitempty"`
+	Status           string   `json:"status"`
+	ReachableSymbols []string `json:"reachable_symbols,omitempty"`
+	// CallChain is a shortest call path from an entrypoint to the first
+	// reachable affected symbol.
+	CallChain []string `json:"call_chain,omitempty"`
+}
+
+// VulnerabilityReachability joins the external dependencies with the OSV
+// advisories affecting their versions and classifies each finding by
+// whether an affected symbol is reachable from the roots. Dependencies
+// whose version cannot be compared get the findings of every advisory of
+// their module, marked VersionUnknown.
+func VulnerabilityReachability(prog *ssa.Program, cg *callgraph.Graph, roots []*ssa.Function, deps []Dependency, advisories []OSVEntry) []VulnFinding {
+	walk := walkCallGraph(cg, roots)
+
+	// Index reachable functions by package and OSV symbol name, keeping the
+	// first function found for each symbol, which has the shortest path
+	reachable := make(map[string]map[string]*ssa.Function)
+	for _, fn := range walk.order {
+		pkgPath := functionPackagePath(fn)
+		if reachable[pkgPath] == nil {
+			reachable[pkgPath] = make(map[string]*ssa.Function)
+		}
+		symbol := osvSymbol(fn)
+		if _, ok := reachable[pkgPath][symbol]; !ok {
+			reachable[pkgPath][symbol] = fn
+		}
+	}
+
+	type findingKey struct{ id, pkg string }
+	seen := make(map[findingKey]bool)
+	var findings []VulnFinding
+
+	for _, dep := range deps {
+		if dep.Internal || dep.Path() == "" {
+			continue
+		}
+		versionUnknown := !semver.IsValid(dep.Version)
+		for _, entry := range advisories {
+			for _, affected := range entry.Affected {
+				module := affected.Package.Name
+				if !inModule(dep.Path(), module) || !versionUnknown && !affected.AffectsVersion(dep.Version) {
+					continue
+				}
+
+				imports := affected.EcosystemSpecific.Imports
+				if len(imports) == 0 {
+					// No package details: the whole module is affected
+					imports = []OSVImport{{Path: module}}
+				}
+
+				for _, imp := range imports {
+					key := findingKey{entry.ID, imp.Path}
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+
+					finding := VulnFinding{
+						ID:             entry.ID,
+						Aliases:        entry.Aliases,
+						Summary:        entry.Summary,
+						Module:         module,
+						Version:        dep.Version,
+						VersionUnknown: versionUnknown,
+						Label:          dep.OriginalLabel,
+						Package:        imp.Path,
+						Symbols:        imp.Symbols,
+						Status:         VulnUnreachable,
+					}
+					if prog.ImportedPackage(imp.Path) != nil {
+						finding.Status = VulnImported
+					}
+
+					var first *ssa.Function
+					for _, symbol := range affectedSymbols(imp, reachable[imp.Path]) {
+						fn := reachable[imp.Path][symbol]
+						if fn == nil {
+							continue
+						}
+						finding.ReachableSymbols = append(finding.ReachableSymbols, symbol)
+						if first == nil || walk.index[fn] < walk.index[first] {
+							first = fn
+						}
+					}
+					if first != nil {
+						finding.Status = VulnReachable
+						finding.CallChain = walk.path(first)
+					}
+					findings = append(findings, finding)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].ID != findings[j].ID {
+			return findings[i].ID < findings[j].ID
+		}
+		return findings[i].Package < findings[j].Package
+	})
+	return findings
+}
+
+// affectedSymbols returns the symbols to check for an import: the listed
+// symbols, or every reachable symbol of the package when none are listed.
+func affectedSymbols(imp OSVImport, reachable map[string]*ssa.Function) []string {
+	if len(imp.Symbols) > 0 {
+		return imp.Symbols
+	}
+	var symbols []string
+	for symbol := range reachable {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// osvSymbol returns the name the Go vulnerability database uses for fn:
+// "Func" for functions and "Type.Method" for methods of T or *T.
+func osvSymbol(fn *ssa.Function) string {
+	if origin := fn.Origin(); origin != nil {
+		fn = origin
+	}
+	recv := fn.Signature.Recv()
+	if recv == nil {
+		return fn.Name()
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name() + "." + fn.Name()
+	}
+	return fn.Name()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/example/go-aspects/aspects/golang/common/analyzer"
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+)
+
+// runDiff implements the diff subcommand. It exits with status 1 when the
+// head result has new callers or calls into sensitive packages.
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	sensitive := flags.String("sensitive", strings.Join(analyzer.DefaultSensitivePackages, ","), "comma-separated import path prefixes of sensitive packages")
+	outputFile := flags.String("output", "", "write the diff JSON to this file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff [flags] <base_result_json> <head_result_json>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	base, err := analyzer.ReadResult(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	head, err := analyzer.ReadResult(flags.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var prefixes []string
+	for _, prefix := range strings.Split(*sensitive, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	diff := analyzer.DiffResults(base, head, prefixes)
+	fmt.Fprintf(os.Stderr, "📊 Functions: +%d -%d, edges: +%d -%d\n",
+		len(diff.AddedFunctions), len(diff.RemovedFunctions), len(diff.AddedEdges), len(diff.RemovedEdges))
+	fmt.Fprintf(os.Stderr, "📦 Newly reachable packages: %d, no longer reachable: %d\n",
+		len(diff.NewlyReachablePackages), len(diff.NoLongerReachablePackages))
+
+	if *outputFile != "" {
+		if err := analyzer.WriteDiff(*outputFile, diff); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		if err := schema.ValidateValue(schema.CallGraphDiff, diff); err != nil {
+			log.Fatalf("invalid diff: %v", err)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if len(diff.SensitivePaths) > 0 {
+		for _, p := range diff.SensitivePaths {
+			fmt.Fprintf(os.Stderr, "🚨 New path into %s: %s\n", p.Package, strings.Join(p.CallChain, " → "))
+		}
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✅ No new paths into sensitive packages\n")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/example/go-aspects/aspects/golang/common/analyzer"
+)
+
+// runExport implements the export subcommand, rendering a result as DOT,
+// GraphML or Mermaid.
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", string(analyzer.FormatDOT), "output format: dot, graphml or mermaid")
+	packages := flags.Bool("packages", false, "collapse functions to package level")
+	origins := flags.Bool("origins", false, "collapse generic instances to their generic origin")
+	prefix := flags.String("prefix", "", "comma-separated import path prefixes of the packages to keep")
+	root := flags.String("root", "", "function ID, entrypoint route such as \"GET /health\", or package import path to start from")
+	depth := flags.Int("depth", 0, "maximum number of calls from --root (0 is unlimited)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export [flags] <result_json> [output_file]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	exportFormat, err := analyzer.ParseExportFormat(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, err := analyzer.ReadResult(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := analyzer.ExportOptions{
+		Format:   exportFormat,
+		Packages: *packages,
+		Origins:  *origins,
+		Root:     *root,
+		Depth:    *depth,
+	}
+	for _, p := range strings.Split(*prefix, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			opts.Prefixes = append(opts.Prefixes, p)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if flags.NArg() == 2 {
+		if file, err = os.Create(flags.Arg(1)); err != nil {
+			log.Fatal(err)
+		}
+		w = file
+	}
+
+	// log.Fatal skips deferred calls, so the file is closed explicitly, and
+	// a failed close of a written export is reported
+	if err := analyzer.Export(w, result, opts); err != nil {
+		if file != nil {
+			file.Close()
+		}
+		log.Fatal(err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// Command callgraph builds the call graph of a Bazel Go target from the
+// packages JSON written by the callgraph aspects.
+//
+// Usage:
+//
+//	callgraph [--loader=export|exportdata|workspace|cwd] [--algorithm=static|cha|rta|vta|rta+vta] [--instances]
+//	          [--workspace=<dir>] [--goroot=<sdk>] [--goos=<os>] [--goarch=<arch>] [--tags=<tags>] [--cgo] [--tests] [--root-only] [--internal] [--packages=<prefixes>] [--all-packages]
+//	          [--aggregate] [--exposure [--sensitive=<prefixes>] [--follow-formatting]] [--taint [--sources=<functions>] [--sinks=<functions>]]
+//	          [--deps=<merged_deps_json> [--osv=<osv_db>]] [--output-format=json|ndjson|sharded|binary] [--strict]
+//	          <packages_json_file|-> <output_file>
+//	callgraph packages [--driver=<gopackagesdriver>] [--dir=<workspace>] [--tests]
+//	          [--output=<file>] <pattern>...
+//	callgraph diff [--sensitive=<prefixes>] [--output=<file>] <base_result_json> <head_result_json>
+//	callgraph summarize [--loader=exportdata|export|workspace|cwd] [--goroot=<sdk>] [--goos=<os>] [--goarch=<arch>]
+//	          [--tags=<tags>] [--cgo] [--result=<result_file>] [--strict] <packages_json_file|-> <summary_file>
+//	callgraph link [--root=<package>] [--output-format=json|ndjson|sharded|binary] [--strict] <output_file> <summary_file>...
+//	callgraph validate [--schema=<schema>] <file>...
+//	callgraph export [--format=dot|graphml|mermaid] [--packages] [--origins] [--prefix=<prefixes>]
+//	          [--root=<function_or_package> [--depth=<n>]] <result_json> [output_file]
+//
+// The packages JSON is a gopackagesdriver response; "-" reads it from stdin.
+// The packages subcommand produces one by querying a gopackagesdriver, such
+// as rules_go's, with the GOPACKAGESDRIVER protocol.
+//
+// The diff subcommand compares two results of the same target and exits with
+// status 1 when the head result adds call paths from the entrypoints into
+// sensitive packages, through a new caller or a new call, so it can gate CI.
+// Its results must keep the calls of the packages that may call into them,
+// with --internal or --all-packages. The export subcommand renders a
+// result, optionally collapsed to packages or generic origins, for design
+// docs and viewers.
+//
+// The summarize subcommand writes the call graph summary of a single package,
+// which the go_library aspects cache per target, and with --result the call
+// graph result of the package linked from it; the link subcommand links
+// the summaries of the packages of a binary into its call graph without
+// analyzing them again.
+//
+// By default a result keeps the calls made from the root package. --internal
+// and --packages keep the calls made from the workspace packages or from the
+// packages matching a prefix instead, together with --root-only those of the
+// root package too. --all-packages keeps the calls of every loaded
+// package, dependencies and standard library included: the result of a small
+// binary then grows from a few hundred edges to over a hundred thousand, and
+// from kilobytes to about a hundred megabytes of JSON.
+//
+// Results are written as a single JSON document by default. For very large
+// call graphs, --output-format=ndjson writes a normalized function table and
+// edge list one record per line, and --output-format=sharded writes a
+// directory with one such file per package, which readers load lazily.
+// --output-format=binary writes the compact encoding of the cgbin package,
+// which interns strings and is versioned for downstream consumers. The diff
+// and export subcommands read results in any format.
+//
+// Results and summaries list diagnostics: the load errors, type errors,
+// skipped packages and fallbacks met during the analysis, with their
+// severity. An analysis that fails still writes an empty result, whose
+// diagnostics tell it apart from a target without calls. With --strict,
+// the command exits with status 1 after writing an output with error
+// diagnostics, so CI can reject incomplete call graphs.
+//
+// Every output is validated against its published JSON Schema, found in the
+// schema package, before it is written, so a malformed result fails the
+// action. The validate subcommand checks existing results, packages JSON
+// and merged dependency files against the same schemas.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/example/go-aspects/aspects/golang/common/analyzer"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "packages" {
+		runPackages(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		runExport(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "summarize" {
+		runSummarize(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "link" {
+		runLink(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		runValidate(os.Args[2:])
+		return
+	}
+
+	loader := flag.String("loader", string(analyzer.LoaderExport), "package loading strategy: export, exportdata, workspace or cwd")
+	algorithm := flag.String("algorithm", string(analyzer.AlgorithmVTA), "call graph algorithm: static, cha, rta, vta or rta+vta")
+	instances := flag.Bool("instances", false, "resolve the calls of each generic instance separately; always on for rta and rta+vta")
+	rootOnly := flag.Bool("root-only", false, "only report calls made from the root package; the default unless --internal, --packages or --all-packages is set")
+	workspace := flag.String("workspace", "", "workspace root holding go.mod; required by the workspace loader")
+	goroot := flag.String("goroot", "", "Go SDK whose go command and standard library are used; loaders running the go command require its bin directory first on PATH")
+	goos := flag.String("goos", "", "target operating system; defaults to the Go SDK's")
+	goarch := flag.String("goarch", "", "target architecture; defaults to the Go SDK's")
+	tags := flag.String("tags", "", "comma-separated build tags of the target")
+	cgo := flag.Bool("cgo", false, "the target is built with cgo, so Go files requiring cgo are loaded instead of their pure Go alternatives")
+	internal := flag.Bool("internal", false, "only report calls made from internal (workspace) packages")
+	tests := flag.Bool("tests", false, "include test packages, use Test, Benchmark and Fuzz functions as roots and report untested functions; the export and exportdata loaders only see the test packages of the packages JSON")
+	allPackages := flag.Bool("all-packages", false, "report calls made from every loaded package, dependencies and standard library included; results grow several hundred times")
+	packagePrefixes := flag.String("packages", "", "comma-separated import path prefixes; only report calls made from matching packages")
+	exposure := flag.Bool("exposure", false, "report the third-party modules and sensitive stdlib packages each discovered route reaches")
+	sensitive := flag.String("sensitive", strings.Join(analyzer.DefaultExposurePackages, ","), "comma-separated import path prefixes of the sensitive stdlib packages of --exposure")
+	followFormatting := flag.Bool("follow-formatting", false, "follow interface calls of Error and String methods in --exposure, which otherwise reach every implementation")
+	taint := flag.Bool("taint", false, "report data flows from user input sources to sinks such as SQL queries, commands and HTTP responses")
+	taintSources := flag.String("sources", strings.Join(analyzer.DefaultTaintSources, ","), "comma-separated source functions of --taint, e.g. net/http.Request.FormValue")
+	taintSinks := flag.String("sinks", strings.Join(analyzer.DefaultTaintSinks, ","), "comma-separated sink functions of --taint; a type in parentheses restricts the first argument, e.g. fmt.Fprint(net/http.ResponseWriter)")
+	aggregate := flag.Bool("aggregate", false, "add package-level and module-level call graphs")
+	depsFile := flag.String("deps", "", "merge_json_deps output; enables reachability analysis and the unused dependency report of its external dependencies")
+	osvPath := flag.String("osv", "", "offline OSV database file or directory; enables vulnerability reachability (requires --deps)")
+	outputFormat := flag.String("output-format", string(analyzer.OutputJSON), "result format: json, ndjson, sharded (a directory of per-package shards) or binary")
+	strict := flag.Bool("strict", false, "exit with status 1 after writing the result when its diagnostics hold errors")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <packages_json_file|-> <output_file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s packages [flags] <pattern>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s diff [flags] <base_result_json> <head_result_json>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s export [flags] <result_json> [output_file]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s summarize [flags] <packages_json_file|-> <summary_file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s link [flags] <output_file> <summary_file>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s validate [flags] <file>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	packagesFile := flag.Arg(0)
+	outputFile := flag.Arg(1)
+
+	mode, err := analyzer.ParseLoaderMode(*loader)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	algo, err := analyzer.ParseAlgorithm(*algorithm)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	format, err := analyzer.ParseOutputFormat(*outputFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "🔍 %s Analysis (loader: %s)\n", algo, mode)
+	fmt.Fprintf(os.Stderr, "📄 Reading packages file: %s\n", packagesFile)
+
+	response, err := analyzer.ReadPackagesFile(packagesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := &analyzer.Config{
+		Loader:                mode,
+		WorkspaceRoot:         *workspace,
+		GoRoot:                *goroot,
+		GOOS:                  *goos,
+		GOARCH:                *goarch,
+		Cgo:                   *cgo,
+		Algorithm:             algo,
+		Instances:             *instances,
+		RootOnly:              *rootOnly,
+		Internal:              *internal,
+		AllPackages:           *allPackages,
+		Tests:                 *tests,
+		Exposure:              *exposure,
+		FollowFormattingCalls: *followFormatting,
+		Taint:                 *taint,
+		Aggregate:             *aggregate,
+		Log:                   os.Stderr,
+	}
+
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			cfg.Tags = append(cfg.Tags, tag)
+		}
+	}
+
+	for _, prefix := range strings.Split(*packagePrefixes, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			cfg.Packages = append(cfg.Packages, prefix)
+		}
+	}
+
+	for _, prefix := range strings.Split(*sensitive, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			cfg.SensitivePackages = append(cfg.SensitivePackages, prefix)
+		}
+	}
+
+	for _, name := range strings.Split(*taintSources, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.TaintSources = append(cfg.TaintSources, name)
+		}
+	}
+
+	for _, name := range strings.Split(*taintSinks, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.TaintSinks = append(cfg.TaintSinks, name)
+		}
+	}
+
+	if *depsFile != "" {
+		deps, err := analyzer.ReadDependencies(*depsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Dependencies = deps
+	}
+
+	if *osvPath != "" {
+		if *depsFile == "" {
+			log.Fatal("--osv requires --deps")
+		}
+		advisories, err := analyzer.LoadOSVDatabase(*osvPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Advisories = advisories
+	}
+
+	// Generate an empty result instead of failing when analysis is not
+	// possible; its diagnostics record why
+	result, err := analyzer.Analyze(cfg, response)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	}
+
+	if err := analyzer.WriteResultFormat(outputFile, result, format); err != nil {
+		log.Fatal(err)
+	}
+	checkDiagnostics(result.Diagnostics, *strict)
+}
+
+// checkDiagnostics summarizes the error diagnostics of an output and, in
+// strict mode, exits with status 1 when there are any.
+func checkDiagnostics(diagnostics []analyzer.Diagnostic, strict bool) {
+	errs := analyzer.Errors(diagnostics)
+	if len(errs) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "❌ %d of %d diagnostics are errors, the call graph is incomplete\n", len(errs), len(diagnostics))
+	if strict {
+		os.Exit(1)
+	}
+}
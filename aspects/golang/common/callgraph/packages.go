@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/example/go-aspects/aspects/golang/common/analyzer"
+)
+
+// runPackages implements the packages subcommand, querying a
+// gopackagesdriver for the patterns and writing its response as a packages
+// JSON for the analysis.
+func runPackages(args []string) {
+	flags := flag.NewFlagSet("packages", flag.ExitOnError)
+	driver := flags.String("driver", os.Getenv("GOPACKAGESDRIVER"), "gopackagesdriver binary (default $GOPACKAGESDRIVER)")
+	dir := flags.String("dir", "", "working directory of the driver, normally the workspace root")
+	tests := flags.Bool("tests", false, "also load the test variants of the packages")
+	outputFile := flags.String("output", "", "write the packages JSON to this file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s packages [flags] <pattern>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *driver == "" {
+		log.Fatal("no gopackagesdriver: set --driver or GOPACKAGESDRIVER")
+	}
+
+	fmt.Fprintf(os.Stderr, "🔄 Querying %s for %v\n", *driver, flags.Args())
+	response, err := analyzer.QueryDriver(&analyzer.DriverConfig{
+		Driver: *driver,
+		Dir:    *dir,
+		Tests:  *tests,
+		Log:    os.Stderr,
+	}, flags.Args()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stdlib := 0
+	for _, pkg := range response.Packages {
+		if analyzer.IsStdlibPackage(pkg.ID) {
+			stdlib++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "📊 Total packages: %d\n", len(response.Packages))
+	fmt.Fprintf(os.Stderr, "🏠 Workspace packages: %d\n", len(response.SourcePackages()))
+	fmt.Fprintf(os.Stderr, "📚 Stdlib packages: %d\n", stdlib)
+	fmt.Fprintf(os.Stderr, "🔗 External packages: %d\n", len(response.ExternalPackages()))
+
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if *outputFile != "" {
+		if file, err = os.Create(*outputFile); err != nil {
+			log.Fatal(err)
+		}
+		w = file
+	}
+	// Close before exiting, and fail when the close loses buffered writes
+	if err := analyzer.WritePackages(w, response); err != nil {
+		if file != nil {
+			file.Close()
+		}
+		log.Fatal(err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
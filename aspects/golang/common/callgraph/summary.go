@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/example/go-aspects/aspects/golang/common/analyzer"
+)
+
+// runSummarize implements the summarize subcommand, writing the call graph
+// summary of the root package of a packages JSON and, with --result, the
+// call graph result of the package linked from that summary alone, so the
+// package is analyzed once for both.
+func runSummarize(args []string) {
+	flags := flag.NewFlagSet("summarize", flag.ExitOnError)
+	loader := flags.String("loader", string(analyzer.LoaderExportData), "package loading strategy: export, exportdata, workspace or cwd")
+	workspace := flags.String("workspace", "", "workspace root holding go.mod; required by the workspace loader")
+	goroot := flags.String("goroot", "", "Go SDK whose go command and standard library are used; loaders running the go command require its bin directory first on PATH")
+	goos := flags.String("goos", "", "target operating system; defaults to the Go SDK's")
+	goarch := flags.String("goarch", "", "target architecture; defaults to the Go SDK's")
+	tags := flags.String("tags", "", "comma-separated build tags of the target")
+	cgo := flags.Bool("cgo", false, "the target is built with cgo, so Go files requiring cgo are loaded instead of their pure Go alternatives")
+	resultFile := flags.String("result", "", "also write the call graph result of the package, linked from its summary, to this file")
+	strict := flags.Bool("strict", false, "exit with status 1 after writing the summary when its diagnostics hold errors")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s summarize [flags] <packages_json_file|-> <summary_file>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	mode, err := analyzer.ParseLoaderMode(*loader)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	response, err := analyzer.ReadPackagesFile(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := &analyzer.Config{
+		Loader:        mode,
+		WorkspaceRoot: *workspace,
+		GoRoot:        *goroot,
+		GOOS:          *goos,
+		GOARCH:        *goarch,
+		Cgo:           *cgo,
+		Log:           os.Stderr,
+	}
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			cfg.Tags = append(cfg.Tags, tag)
+		}
+	}
+
+	summary, err := analyzer.Summarize(cfg, response)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := analyzer.WriteSummary(flags.Arg(1), summary); err != nil {
+		log.Fatal(err)
+	}
+	if *resultFile != "" {
+		result, err := analyzer.LinkSummaries([]*analyzer.PackageSummary{summary}, summary.PackageID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := analyzer.WriteResultFormat(*resultFile, result, analyzer.OutputJSON); err != nil {
+			log.Fatal(err)
+		}
+	}
+	checkDiagnostics(summary.Diagnostics, *strict)
+}
+
+// runLink implements the link subcommand, linking package summaries into
+// the call graph result of a binary.
+func runLink(args []string) {
+	flags := flag.NewFlagSet("link", flag.ExitOnError)
+	root := flags.String("root", "", "package ID or import path of the main package; defaults to the first summary")
+	outputFormat := flags.String("output-format", string(analyzer.OutputJSON), "result format: json, ndjson, sharded (a directory of per-package shards) or binary")
+	strict := flags.Bool("strict", false, "exit with status 1 after writing the result when its diagnostics hold errors")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s link [flags] <output_file> <summary_file>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	format, err := analyzer.ParseOutputFormat(*outputFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var summaries []*analyzer.PackageSummary
+	for _, path := range flags.Args()[1:] {
+		summary, err := analyzer.ReadSummary(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		summaries = append(summaries, summary)
+	}
+
+	result, err := analyzer.LinkSummaries(summaries, *root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "🔗 Linked %d package summaries: %d functions, %d edges\n", len(summaries), result.TotalFuncs, result.TotalEdges)
+
+	if err := analyzer.WriteResultFormat(flags.Arg(0), result, format); err != nil {
+		log.Fatal(err)
+	}
+	checkDiagnostics(result.Diagnostics, *strict)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/example/go-aspects/aspects/golang/common/analyzer"
+	"github.com/example/go-aspects/aspects/golang/common/schema"
+)
+
+// runValidate implements the validate subcommand, checking output files
+// against the published JSON Schemas. It exits with status 1 when any file
+// is invalid.
+func runValidate(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	schemaName := flags.String("schema", "", "schema to validate JSON documents against, optionally with a fragment; detected from the document by default ("+strings.Join(schema.Names(), ", ")+")")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s validate [flags] <file>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flags.Args() {
+		ref, err := analyzer.ValidateFile(path, *schemaName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "✅ %s matches %s (schema version %d)\n", path, ref, schema.Version)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,344 @@
+// Package cgbin reads and writes the compact binary encoding of call graph
+// results.
+//
+// An encoded graph starts with the magic bytes and the schema version,
+// followed by length-prefixed sections:
+//
+//	magic      "\x00CGB"
+//	version    uvarint
+//	strings    uvarint count, then uvarint length and bytes of each string
+//	header     uvarint length and the JSON of the result's reports
+//	functions  uvarint count, then the fields of each function as string
+//	           indexes, lists prefixed with their length
+//	edges      uvarint count, then one column per edge field: caller and
+//	           callee function indexes, file, line, column, kind, mode,
+//	           receiver type and scope
+//
+// Every string is interned in the string table and referenced by index, so
+// the package paths, files and type names shared by many functions and
+// edges are stored once. Integers are unsigned varints.
+//
+// The package has no dependencies beyond the standard library, so
+// consumers can decode results without the analyzer.
+package cgbin
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SchemaVersion is the version of the encoding written by Write. Read
+// accepts this version and older ones.
+const SchemaVersion = 1
+
+// Magic identifies an encoded graph. Its leading NUL byte never starts a
+// JSON document.
+var Magic = []byte("\x00CGB")
+
+// maxLength bounds the integers read from the input.
+const maxLength = 1 << 31
+
+// Graph is a decoded call graph.
+type Graph struct {
+	// SchemaVersion is the version the graph was encoded with.
+	SchemaVersion int
+	// Header is the JSON of the result without its call_graph, functions
+	// and call_edges sections.
+	Header []byte
+	// Functions is the function table; edges reference it by index.
+	Functions []Function
+	Edges     []Edge
+}
+
+// Function describes a function; the fields match the functions section
+// of JSON results.
+type Function struct {
+	ID             string
+	Name           string
+	Receiver       string
+	Package        string
+	Signature      string
+	Parameters     []string
+	Returns        []string
+	TypeParams     []string
+	Origin         string
+	TypeArgs       []string
+	InstantiatedAt []string
+	Coverage       string
+}
+
+// Edge is a call edge between two functions of the function table.
+type Edge struct {
+	Caller       int
+	Callee       int
+	File         string
+	Line         int
+	Column       int
+	Kind         string
+	Mode         string
+	ReceiverType string
+	Scope        string
+}
+
+// IsBinary reports whether data starts with the magic bytes.
+func IsBinary(data []byte) bool {
+	return bytes.HasPrefix(data, Magic)
+}
+
+// Write encodes the graph with the current schema version.
+func Write(w io.Writer, g *Graph) error {
+	strs := newStringTable()
+	for _, fn := range g.Functions {
+		strs.add(fn.ID, fn.Name, fn.Receiver, fn.Package, fn.Signature, fn.Origin, fn.Coverage)
+		strs.add(fn.Parameters...)
+		strs.add(fn.Returns...)
+		strs.add(fn.TypeParams...)
+		strs.add(fn.TypeArgs...)
+		strs.add(fn.InstantiatedAt...)
+	}
+	for _, edge := range g.Edges {
+		if edge.Caller < 0 || edge.Caller >= len(g.Functions) || edge.Callee < 0 || edge.Callee >= len(g.Functions) {
+			return fmt.Errorf("edge references function %d or %d outside the function table", edge.Caller, edge.Callee)
+		}
+		strs.add(edge.File, edge.Kind, edge.Mode, edge.ReceiverType, edge.Scope)
+	}
+
+	e := &encoder{w: bufio.NewWriter(w)}
+	e.bytes(Magic)
+	e.uint(SchemaVersion)
+
+	e.uint(len(strs.list))
+	for _, s := range strs.list {
+		e.uint(len(s))
+		e.bytes([]byte(s))
+	}
+
+	e.uint(len(g.Header))
+	e.bytes(g.Header)
+
+	e.uint(len(g.Functions))
+	for _, fn := range g.Functions {
+		for _, s := range []string{fn.ID, fn.Name, fn.Receiver, fn.Package, fn.Signature} {
+			e.uint(strs.index[s])
+		}
+		for _, list := range [][]string{fn.Parameters, fn.Returns, fn.TypeParams} {
+			e.strings(strs, list)
+		}
+		e.uint(strs.index[fn.Origin])
+		e.strings(strs, fn.TypeArgs)
+		e.strings(strs, fn.InstantiatedAt)
+		e.uint(strs.index[fn.Coverage])
+	}
+
+	// Edges are written column by column, which keeps similar values
+	// together for downstream compression
+	e.uint(len(g.Edges))
+	columns := []func(Edge) int{
+		func(edge Edge) int { return edge.Caller },
+		func(edge Edge) int { return edge.Callee },
+		func(edge Edge) int { return strs.index[edge.File] },
+		func(edge Edge) int { return edge.Line },
+		func(edge Edge) int { return edge.Column },
+		func(edge Edge) int { return strs.index[edge.Kind] },
+		func(edge Edge) int { return strs.index[edge.Mode] },
+		func(edge Edge) int { return strs.index[edge.ReceiverType] },
+		func(edge Edge) int { return strs.index[edge.Scope] },
+	}
+	for _, column := range columns {
+		for _, edge := range g.Edges {
+			e.uint(column(edge))
+		}
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// Read decodes a graph written by Write with this or an older schema
+// version.
+func Read(r io.Reader) (*Graph, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+
+	magic := d.bytes(len(Magic))
+	if d.err == nil && !IsBinary(magic) {
+		return nil, errors.New("not a binary call graph")
+	}
+	g := &Graph{SchemaVersion: d.uint()}
+	if d.err == nil && (g.SchemaVersion < 1 || g.SchemaVersion > SchemaVersion) {
+		return nil, fmt.Errorf("unsupported binary call graph schema version %d (want at most %d)", g.SchemaVersion, SchemaVersion)
+	}
+
+	var strs []string
+	for n := d.uint(); n > 0 && d.err == nil; n-- {
+		strs = append(strs, string(d.bytes(d.uint())))
+	}
+	str := func() string {
+		i := d.uint()
+		if i >= len(strs) {
+			d.fail(fmt.Errorf("string index %d out of range", i))
+			return ""
+		}
+		return strs[i]
+	}
+	list := func() []string {
+		var l []string
+		for n := d.uint(); n > 0 && d.err == nil; n-- {
+			l = append(l, str())
+		}
+		return l
+	}
+
+	g.Header = d.bytes(d.uint())
+
+	for n := d.uint(); n > 0 && d.err == nil; n-- {
+		var fn Function
+		fn.ID, fn.Name, fn.Receiver, fn.Package, fn.Signature = str(), str(), str(), str(), str()
+		fn.Parameters, fn.Returns, fn.TypeParams = list(), list(), list()
+		fn.Origin = str()
+		fn.TypeArgs, fn.InstantiatedAt = list(), list()
+		fn.Coverage = str()
+		g.Functions = append(g.Functions, fn)
+	}
+
+	function := func() int {
+		i := d.uint()
+		if i >= len(g.Functions) {
+			d.fail(fmt.Errorf("function index %d out of range", i))
+		}
+		return i
+	}
+	// The caller column sizes the edges as it is read, so a corrupt count
+	// fails on the missing data rather than allocating for it
+	for n := d.uint(); n > 0 && d.err == nil; n-- {
+		g.Edges = append(g.Edges, Edge{Caller: function()})
+	}
+	columns := []func(*Edge){
+		func(edge *Edge) { edge.Callee = function() },
+		func(edge *Edge) { edge.File = str() },
+		func(edge *Edge) { edge.Line = d.uint() },
+		func(edge *Edge) { edge.Column = d.uint() },
+		func(edge *Edge) { edge.Kind = str() },
+		func(edge *Edge) { edge.Mode = str() },
+		func(edge *Edge) { edge.ReceiverType = str() },
+		func(edge *Edge) { edge.Scope = str() },
+	}
+	for _, column := range columns {
+		for i := range g.Edges {
+			column(&g.Edges[i])
+		}
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	return g, nil
+}
+
+// stringTable interns strings; the empty string is always index 0.
+type stringTable struct {
+	list  []string
+	index map[string]int
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{list: []string{""}, index: map[string]int{"": 0}}
+}
+
+func (t *stringTable) add(strs ...string) {
+	for _, s := range strs {
+		if _, ok := t.index[s]; !ok {
+			t.index[s] = len(t.list)
+			t.list = append(t.list, s)
+		}
+	}
+}
+
+// encoder writes varints and bytes, keeping the first error.
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *encoder) uint(v int) {
+	n := binary.PutUvarint(e.buf[:], uint64(v))
+	e.bytes(e.buf[:n])
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) strings(strs *stringTable, list []string) {
+	e.uint(len(list))
+	for _, s := range list {
+		e.uint(strs.index[s])
+	}
+}
+
+// decoder reads varints and bytes, keeping the first error; after an error
+// it returns zero values.
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) uint() int {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.fail(truncated(err))
+		return 0
+	}
+	if v > maxLength {
+		d.fail(fmt.Errorf("value %d out of range", v))
+		return 0
+	}
+	return int(v)
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	// Large lengths are read incrementally, so a corrupt length fails on
+	// the missing data rather than allocating for it
+	if n > 1<<16 {
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+			d.fail(truncated(err))
+			return nil
+		}
+		return buf.Bytes()
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.fail(truncated(err))
+		return nil
+	}
+	return b
+}
+
+// truncated reports a premature end of input as such.
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("truncated binary call graph")
+	}
+	return err
+}
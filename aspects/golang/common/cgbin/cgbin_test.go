@@ -0,0 +1,128 @@
+package cgbin
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testGraph returns a graph using every field, with strings shared between
+// functions and edges.
+func testGraph() *Graph {
+	return &Graph{
+		SchemaVersion: SchemaVersion,
+		Header:        []byte(`{"schema_version":1,"package_id":"//src/main:main","algorithm":"VTA"}`),
+		Functions: []Function{
+			{
+				ID:         "example.com/app.main",
+				Name:       "main",
+				Package:    "example.com/app",
+				Signature:  "func()",
+				Coverage:   "covered",
+				Parameters: []string{},
+			},
+			{
+				ID:         "(*example.com/app.Server).Serve",
+				Name:       "Serve",
+				Receiver:   "*example.com/app.Server",
+				Package:    "example.com/app",
+				Signature:  "func(addr string) error",
+				Parameters: []string{"addr string"},
+				Returns:    []string{"error"},
+			},
+			{
+				ID:             "example.com/app.Map[string]",
+				Name:           "Map",
+				Package:        "example.com/app",
+				Signature:      "func(s []string) []string",
+				Parameters:     []string{"s []string"},
+				Returns:        []string{"[]string"},
+				TypeParams:     []string{"T any"},
+				Origin:         "example.com/app.Map",
+				TypeArgs:       []string{"string"},
+				InstantiatedAt: []string{"main.go:9:5", "main.go:14:2"},
+			},
+		},
+		Edges: []Edge{
+			{Caller: 0, Callee: 1, File: "main.go", Line: 12, Column: 7, Kind: "static", Mode: "call", Scope: "internal"},
+			{Caller: 0, Callee: 2, File: "main.go", Line: 14, Column: 2, Kind: "static", Mode: "go", Scope: "internal"},
+			{Caller: 1, Callee: 1, File: "server.go", Line: 30, Column: 3, Kind: "interface", Mode: "defer", ReceiverType: "example.com/app.Service", Scope: "internal"},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := testGraph()
+	var buf bytes.Buffer
+	if err := Write(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	if !IsBinary(buf.Bytes()) {
+		t.Fatal("encoded graph does not start with the magic bytes")
+	}
+
+	got, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Empty lists decode as nil
+	want.Functions[0].Parameters = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read(Write(g)) = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestRoundTripEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, &Graph{Header: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Graph{SchemaVersion: SchemaVersion, Header: []byte(`{}`)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read(Write(empty)) = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteRejectsDanglingEdge(t *testing.T) {
+	g := testGraph()
+	g.Edges = append(g.Edges, Edge{Caller: 0, Callee: len(g.Functions)})
+	if err := Write(&bytes.Buffer{}, g); err == nil {
+		t.Error("Write of an edge outside the function table succeeded")
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testGraph()); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	newer := append(append([]byte{}, Magic...), SchemaVersion+1)
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"json", []byte(`{"call_graph": {}}`), "not a binary call graph"},
+		{"newer version", newer, "unsupported binary call graph schema version"},
+		{"truncated", encoded[:len(encoded)-5], ""},
+		{"header only", encoded[:len(Magic)+1], ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.data))
+			if err == nil {
+				t.Fatal("Read succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Read error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
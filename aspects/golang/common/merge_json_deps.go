@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+
+	"github.com/example/go-aspects/aspects/golang/common/schema"
 )
 
 // Node represents a dependency node in the JSON structure
@@ -16,7 +18,8 @@ type Node struct {
 
 // JSONData represents the structure of the JSON files
 type JSONData struct {
-	Nodes []json.RawMessage `json:"nodes"`
+	SchemaVersion int               `json:"schema_version"`
+	Nodes         []json.RawMessage `json:"nodes"`
 }
 
 func main() {
@@ -82,7 +85,7 @@ func mergeJSONFiles(outputFile string, inputFiles []string) error {
 	}
 
 	// Create result structure
-	result := JSONData{Nodes: mergedNodes}
+	result := JSONData{SchemaVersion: schema.Version, Nodes: mergedNodes}
 
 	// Ensure output directory exists
 	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
@@ -95,6 +98,11 @@ func mergeJSONFiles(outputFile string, inputFiles []string) error {
 		return fmt.Errorf("failed to marshal result: %v", err)
 	}
 
+	// Fail on malformed nodes rather than passing them downstream
+	if err := schema.Validate(schema.Dependencies, resultData); err != nil {
+		return fmt.Errorf("invalid merged dependencies: %v", err)
+	}
+
 	if err := ioutil.WriteFile(outputFile, resultData, 0644); err != nil {
 		return fmt.Errorf("failed to write output file: %v", err)
 	}
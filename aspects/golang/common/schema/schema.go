@@ -0,0 +1,423 @@
+// Package schema publishes the JSON Schemas of the documents the Go aspects
+// and tools write, and validates documents against them.
+//
+// The schemas follow JSON Schema draft 2020-12. The validator implements
+// the keywords the published schemas use: $ref, allOf, type, const, enum,
+// minimum, minLength, required, properties, additionalProperties and
+// items.
+package schema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Version is the schema_version of the documents the schemas describe. It
+// is bumped whenever a schema changes incompatibly.
+const Version = 1
+
+// Names of the published schemas.
+const (
+	// CallGraphResult describes call graph results written as JSON.
+	CallGraphResult = "callgraph_result"
+	// CallGraphRecord describes the lines of NDJSON results and shards.
+	CallGraphRecord = "callgraph_record"
+	// CallGraphSummary describes the per-package summaries the summarize
+	// subcommand writes and the link subcommand reads.
+	CallGraphSummary = "callgraph_summary"
+	// CallGraphDiff describes the output of the diff subcommand.
+	CallGraphDiff = "callgraph_diff"
+	// Dependencies describes the {"nodes": [...]} files of the dependency
+	// aspects and merge_json_deps.
+	Dependencies = "dependencies"
+	// Packages describes the packages JSON given to the callgraph tool.
+	Packages = "packages"
+)
+
+// Fragments of the published schemas validating parts of documents.
+const (
+	// ResultHeader is the result without its graph sections, as written
+	// at the start of normalized and binary results.
+	ResultHeader = CallGraphResult + "#/$defs/header"
+	// ResultFunction is an entry of the functions section of a result.
+	ResultFunction = CallGraphResult + "#/$defs/function"
+	// RecordEdge is an edge of a normalized result.
+	RecordEdge = CallGraphRecord + "#/$defs/edge"
+	// ShardManifest is the manifest of a sharded result.
+	ShardManifest = CallGraphRecord + "#/$defs/shard_manifest"
+)
+
+// maxErrors bounds the violations reported for one document.
+const maxErrors = 20
+
+//go:embed *.schema.json
+var files embed.FS
+
+// JSON pointer token escaping, RFC 6901.
+var (
+	escapeToken   = strings.NewReplacer("~", "~0", "/", "~1")
+	unescapeToken = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+var (
+	loadOnce sync.Once
+	schemas  map[string]interface{}
+	loadErr  error
+)
+
+// load parses the embedded schemas, keyed by file name.
+func load() (map[string]interface{}, error) {
+	loadOnce.Do(func() {
+		entries, err := files.ReadDir(".")
+		if err != nil {
+			loadErr = err
+			return
+		}
+		schemas = make(map[string]interface{})
+		for _, entry := range entries {
+			data, err := files.ReadFile(entry.Name())
+			if err != nil {
+				loadErr = err
+				return
+			}
+			node, err := decode(data)
+			if err != nil {
+				loadErr = fmt.Errorf("invalid schema %s: %v", entry.Name(), err)
+				return
+			}
+			schemas[entry.Name()] = node
+		}
+	})
+	return schemas, loadErr
+}
+
+// Names returns the names of the published schemas.
+func Names() []string {
+	entries, _ := files.ReadDir(".")
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".schema.json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Source returns the published JSON Schema document of a schema name.
+func Source(name string) ([]byte, error) {
+	data, err := files.ReadFile(name + ".schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown schema %q (want one of %s)", name, strings.Join(Names(), ", "))
+	}
+	return data, nil
+}
+
+// ValidationError lists the violations of a document, each prefixed with
+// the JSON pointer of the offending value.
+type ValidationError struct {
+	Schema     string
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("document does not match schema %s:\n  %s", e.Schema, strings.Join(e.Violations, "\n  "))
+}
+
+// Validate validates a JSON document against a schema reference: a schema
+// name, optionally followed by a fragment such as ResultHeader.
+func Validate(ref string, data []byte) error {
+	value, err := decode(data)
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	return validateValue(ref, value)
+}
+
+// ValidateValue validates the JSON encoding of v against a schema
+// reference.
+func ValidateValue(ref string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal document: %v", err)
+	}
+	return Validate(ref, data)
+}
+
+func validateValue(ref string, value interface{}) error {
+	all, err := load()
+	if err != nil {
+		return err
+	}
+	name, fragment, _ := strings.Cut(ref, "#")
+	file := name + ".schema.json"
+	if _, ok := all[file]; !ok {
+		return fmt.Errorf("unknown schema %q (want one of %s)", name, strings.Join(Names(), ", "))
+	}
+	v := &validator{schemas: all, refs: make(map[string]resolved)}
+	node, file, err := v.resolve(file, file+"#"+fragment)
+	if err != nil {
+		return err
+	}
+	v.validate(node, file, value, "")
+	if len(v.violations) > 0 {
+		sort.Strings(v.violations)
+		if v.dropped > 0 {
+			v.violations = append(v.violations, fmt.Sprintf("... and %d more", v.dropped))
+		}
+		return &ValidationError{Schema: ref, Violations: v.violations}
+	}
+	return nil
+}
+
+// decode parses JSON keeping numbers as json.Number, so integers can be
+// told apart from other numbers.
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the document")
+	}
+	return value, nil
+}
+
+type validator struct {
+	schemas    map[string]interface{}
+	refs       map[string]resolved
+	violations []string
+	// dropped counts the violations beyond maxErrors
+	dropped int
+}
+
+// resolved is a schema node a $ref points to and the file holding it.
+type resolved struct {
+	node interface{}
+	file string
+}
+
+func (v *validator) fail(ptr, format string, args ...interface{}) {
+	if len(v.violations) == maxErrors {
+		v.dropped++
+		return
+	}
+	if ptr == "" {
+		ptr = "/"
+	}
+	v.violations = append(v.violations, ptr+": "+fmt.Sprintf(format, args...))
+}
+
+// resolve returns the schema node a $ref points to and the file holding it.
+// Refs are relative to the file of the referring schema.
+func (v *validator) resolve(base, ref string) (interface{}, string, error) {
+	target, fragment, _ := strings.Cut(ref, "#")
+	file := base
+	if target != "" {
+		file = path.Base(target)
+	}
+	node, ok := v.schemas[file]
+	if !ok {
+		return nil, "", fmt.Errorf("unresolved schema reference %q", ref)
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(fragment, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = unescapeToken.Replace(token)
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, "", fmt.Errorf("unresolved schema reference %q", ref)
+		}
+		if node, ok = object[token]; !ok {
+			return nil, "", fmt.Errorf("unresolved schema reference %q", ref)
+		}
+	}
+	return node, file, nil
+}
+
+func (v *validator) validate(node interface{}, file string, value interface{}, ptr string) {
+	switch node := node.(type) {
+	case bool:
+		if !node {
+			v.fail(ptr, "not allowed")
+		}
+		return
+	case map[string]interface{}:
+		v.validateObject(node, file, value, ptr)
+	}
+}
+
+func (v *validator) validateObject(node map[string]interface{}, file string, value interface{}, ptr string) {
+	if ref, ok := node["$ref"].(string); ok {
+		key := file + " " + ref
+		target, ok := v.refs[key]
+		if !ok {
+			node, file, err := v.resolve(file, ref)
+			if err != nil {
+				v.fail(ptr, "%v", err)
+			}
+			target = resolved{node, file}
+			v.refs[key] = target
+		}
+		if target.node != nil {
+			v.validate(target.node, target.file, value, ptr)
+		}
+	}
+	if all, ok := node["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			v.validate(sub, file, value, ptr)
+		}
+	}
+
+	if t, ok := node["type"]; ok && !matchesType(t, value) {
+		v.fail(ptr, "got %s, want %s", typeOf(value), typeNames(t))
+		return
+	}
+	if c, ok := node["const"]; ok && !equal(c, value) {
+		v.fail(ptr, "got %s, want %s", encode(value), encode(c))
+	}
+	if enum, ok := node["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || equal(e, value)
+		}
+		if !found {
+			var want []string
+			for _, e := range enum {
+				want = append(want, encode(e))
+			}
+			v.fail(ptr, "got %s, want one of %s", encode(value), strings.Join(want, ", "))
+		}
+	}
+
+	switch value := value.(type) {
+	case json.Number:
+		if min, ok := node["minimum"].(json.Number); ok {
+			if n, _ := value.Float64(); n < number(min) {
+				v.fail(ptr, "got %s, want at least %s", value, min)
+			}
+		}
+	case string:
+		if min, ok := node["minLength"].(json.Number); ok && float64(utf8.RuneCountInString(value)) < number(min) {
+			v.fail(ptr, "got %q, want at least %s characters", value, min)
+		}
+	case []interface{}:
+		if items, ok := node["items"]; ok {
+			for i, item := range value {
+				v.validate(items, file, item, ptr+"/"+strconv.Itoa(i))
+			}
+		}
+	case map[string]interface{}:
+		if required, ok := node["required"].([]interface{}); ok {
+			for _, name := range required {
+				if name, ok := name.(string); ok {
+					if _, ok := value[name]; !ok {
+						v.fail(ptr, "missing required property %q", name)
+					}
+				}
+			}
+		}
+		properties, _ := node["properties"].(map[string]interface{})
+		additional, hasAdditional := node["additionalProperties"]
+		for key := range value {
+			child := ptr + "/" + key
+			if strings.ContainsAny(key, "~/") {
+				child = ptr + "/" + escapeToken.Replace(key)
+			}
+			if sub, ok := properties[key]; ok {
+				v.validate(sub, file, value[key], child)
+			} else if hasAdditional {
+				if additional == false {
+					v.fail(child, "unknown property")
+				} else {
+					v.validate(additional, file, value[key], child)
+				}
+			}
+		}
+	}
+}
+
+// typeOf returns the JSON Schema type of a decoded value.
+func typeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// matchesType reports whether the value has one of the types of a type
+// keyword; integers are numbers.
+func matchesType(t interface{}, value interface{}) bool {
+	actual := typeOf(value)
+	types, ok := t.([]interface{})
+	if !ok {
+		types = []interface{}{t}
+	}
+	for _, want := range types {
+		if want == actual || (want == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeNames(t interface{}) string {
+	types, ok := t.([]interface{})
+	if !ok {
+		return fmt.Sprint(t)
+	}
+	var names []string
+	for _, name := range types {
+		names = append(names, fmt.Sprint(name))
+	}
+	return strings.Join(names, " or ")
+}
+
+func number(n json.Number) float64 {
+	f, _ := n.Float64()
+	return f
+}
+
+// equal compares decoded values, numbers by value.
+func equal(a, b interface{}) bool {
+	if an, ok := a.(json.Number); ok {
+		bn, ok := b.(json.Number)
+		return ok && number(an) == number(bn)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func encode(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(data) > 80 {
+		return string(data[:77]) + "..."
+	}
+	return string(data)
+}
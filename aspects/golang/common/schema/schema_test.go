@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateFixtures(t *testing.T) {
+	tests := []struct {
+		file string
+		ref  string
+		// violations are the expected violations, none for a valid fixture
+		violations []string
+	}{
+		{
+			file: "result_valid.json",
+			ref:  CallGraphResult,
+		},
+		{
+			file: "result_invalid.json",
+			ref:  CallGraphResult,
+			violations: []string{
+				`/call_edges/0/kind: got "virtual", want one of "static", "dynamic", "interface"`,
+				`/call_edges/0/line: got -1, want at least 0`,
+				`/call_edges/0: missing required property "scope"`,
+				`/functions/example.com~1app.serve: missing required property "signature"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Validate(tt.ref, data)
+			if len(tt.violations) == 0 {
+				if err != nil {
+					t.Fatalf("Validate(%s) = %v, want no error", tt.ref, err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate(%s) = %v, want a ValidationError", tt.ref, err)
+			}
+			if !reflect.DeepEqual(verr.Violations, tt.violations) {
+				t.Errorf("violations:\n  %q\nwant:\n  %q", verr.Violations, tt.violations)
+			}
+		})
+	}
+}
+
+func TestValidateFragment(t *testing.T) {
+	function := []byte(`{"id": "example.com/app.main", "name": "main", "package": "example.com/app", "signature": "func()", "parameters": [], "returns": []}`)
+	if err := Validate(ResultFunction, function); err != nil {
+		t.Errorf("Validate(%s) = %v, want no error", ResultFunction, err)
+	}
+	if err := Validate(ResultFunction, []byte(`{"id": ""}`)); err == nil {
+		t.Errorf("Validate(%s) of a function without fields succeeded", ResultFunction)
+	}
+}
+
+func TestSchemasParse(t *testing.T) {
+	for _, name := range Names() {
+		if _, err := Source(name); err != nil {
+			t.Errorf("Source(%q) = %v", name, err)
+		}
+	}
+	if _, err := load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate("unknown", []byte(`{}`)); err == nil {
+		t.Error("Validate of an unknown schema succeeded")
+	}
+}
@@ -1,5 +1,7 @@
 """Utility functions for Go dependency analysis and version extraction."""
 
+load("@rules_go//go:def.bzl", "GoArchive")
+
 # load("@go_versions_extracted//:versions.bzl", "get_go_version_from_query")
 # Fallback function for when go_versions_extracted is not available
 def get_go_version_from_query(repo_name):
@@ -108,7 +110,105 @@ def _compute_package_version_name(target):
         
     return target_path
 
+GO_TOOLCHAIN_TYPE = "@rules_go//go:toolchain"
+
+# schema_version of the JSON files the aspects write; must match the Go
+# schema.Version of //aspects/golang/common/schema
+SCHEMA_VERSION = 1
+
+def _as_depset(files):
+    """Return files as a depset; older rules_go releases expose SDK files as lists."""
+    if type(files) == "depset":
+        return files
+    return depset(files)
+
+def _get_go_sdk_args(ctx, target):
+    """Return the callgraph flags naming the Go SDK, platform, cgo setting and build tags, and the SDK files they need.
+
+    Cgo is enabled when rules_go compiled the target with it, in the mode
+    the Go toolchain was configured with; pure mode and targets without a
+    GoArchive disable it.
+
+    The exportdata loader type-checks the standard library from the SDK
+    sources, ignoring function bodies, so the inputs leave out the SDK's
+    precompiled libraries, which Go SDKs no longer ship since Go 1.20. The
+    go binary and tools are kept for the loaders that run the go command.
+
+    The aspect using this must declare GO_TOOLCHAIN_TYPE in its toolchains.
+    """
+    sdk = ctx.toolchains[GO_TOOLCHAIN_TYPE].sdk
+    args = [
+        "--goroot=" + sdk.root_file.dirname,
+        "--goos=" + sdk.goos,
+        "--goarch=" + sdk.goarch,
+    ]
+    mode = getattr(target[GoArchive], "mode", None) if GoArchive in target else None
+    if mode and not getattr(mode, "pure", True):
+        args.append("--cgo")
+    tags = getattr(ctx.rule.attr, "gotags", None)
+    if tags:
+        args.append("--tags=" + ",".join(tags))
+
+    inputs = depset(
+        [sdk.go, sdk.root_file],
+        transitive = [_as_depset(sdk.srcs), _as_depset(sdk.headers), _as_depset(sdk.tools)],
+    )
+    return args, inputs
+
+def _get_go_packages_json(ctx, target, import_path, go_sources):
+    """Return the packages JSON of a Go target for the exportdata loader, and the files it references.
+
+    The target is the root package, loaded from its sources. Its transitive
+    dependencies come from its GoArchive: each is listed with its sources,
+    from which the loader reads its imports and the platform's build
+    constraints select, and the export file rules_go compiled for it. The
+    loader takes package names from the export data and type-checks the
+    standard library from the sources of the Go SDK.
+    """
+    source_paths = [f.path for f in go_sources]
+    root = {
+        "ID": str(ctx.label),
+        "Name": ctx.label.name,
+        "PkgPath": import_path,
+        "GoFiles": source_paths,
+        "CompiledGoFiles": source_paths,
+        "Imports": {},
+        "ExportFile": "",
+    }
+    packages = [root]
+    files = []
+
+    if GoArchive in target:
+        archive = target[GoArchive]
+        for dep in archive.direct:
+            root["Imports"][dep.data.importpath] = str(dep.data.label)
+        for data in archive.transitive.to_list():
+            if data.label == target.label:
+                continue
+            export_file = getattr(data, "export_file", None) or data.file
+            dep_sources = [f for f in data.srcs if f.path.endswith(".go")]
+            packages.append({
+                "ID": str(data.label),
+                "PkgPath": data.importpath,
+                "GoFiles": [f.path for f in dep_sources],
+                "ExportFile": export_file.path,
+            })
+            files.append(export_file)
+            files.extend(dep_sources)
+
+    response = {
+        "schema_version": SCHEMA_VERSION,
+        "NotHandled": False,
+        "Compiler": "gc",
+        "Arch": ctx.toolchains[GO_TOOLCHAIN_TYPE].sdk.goarch,
+        "Roots": [str(ctx.label)],
+        "Packages": packages,
+    }
+    return json.encode(response), depset(files)
+
 # Public API exports
 compute_package_version_name = _compute_package_version_name
 get_go_name_version_and_import_path = _get_go_name_version_and_import_path
-get_go_dependency_labels = _get_go_dependency_labels
\ No newline at end of file
+get_go_dependency_labels = _get_go_dependency_labels
+get_go_sdk_args = _get_go_sdk_args
+get_go_packages_json = _get_go_packages_json
@@ -0,0 +1,8 @@
+"""Provider for collecting per-package Go call graph summaries through Bazel aspects."""
+
+EndorGoCallgraphSummaryInfo = provider(
+    doc = "Provider for collecting per-package Go call graph summaries",
+    fields = {
+        "summaries": "Depset of call graph summary files of the target and its transitive dependencies",
+    },
+)
@@ -1,6 +1,6 @@
 """Go binary dependency analysis aspects for Bazel 6.5 and rules_go 0.35.0."""
 
-load("//aspects/golang/common:utils.bzl", "compute_package_version_name", "get_go_dependency_labels", "get_go_name_version_and_import_path")
+load("//aspects/golang/common:utils.bzl", "SCHEMA_VERSION", "compute_package_version_name", "get_go_dependency_labels", "get_go_name_version_and_import_path")
 load("//aspects/golang/provider:endor_go_dependency_info.bzl", "EndorGoDependencyInfo")
 
 def _endor_go_binary_resolve_dependencies(target, ctx):
@@ -45,7 +45,7 @@ def _endor_go_binary_resolve_dependencies(target, ctx):
 
     ctx.actions.write(
         output = output_json,
-        content = '{"nodes": [' + json_content + ']}',
+        content = '{"schema_version": ' + str(SCHEMA_VERSION) + ', "nodes": [' + json_content + ']}',
     )
 
     # Collect all dependency files to merge
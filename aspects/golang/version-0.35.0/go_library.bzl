@@ -1,6 +1,6 @@
 """Go library dependency analysis aspects for Bazel 6.5 and rules_go 0.35.0."""
 
-load("//aspects/golang/common:utils.bzl", "compute_package_version_name", "get_go_dependency_labels", "get_go_name_version_and_import_path")
+load("//aspects/golang/common:utils.bzl", "GO_TOOLCHAIN_TYPE", "SCHEMA_VERSION", "compute_package_version_name", "get_go_dependency_labels", "get_go_name_version_and_import_path", "get_go_packages_json", "get_go_sdk_args")
 load("//aspects/golang/provider:endor_go_dependency_info.bzl", "EndorGoDependencyInfo")
 
 def _endor_go_library_resolve_dependencies(target, ctx):
@@ -45,7 +45,7 @@ def _endor_go_library_resolve_dependencies(target, ctx):
 
     ctx.actions.write(
         output = output_json,
-        content = '{"nodes": [' + json_content + ']}',
+        content = '{"schema_version": ' + str(SCHEMA_VERSION) + ', "nodes": [' + json_content + ']}',
     )
 
     # Collect all dependency files to merge
@@ -84,7 +84,7 @@ internal_endor_go_library_resolve_dependencies = aspect(
 )
 
 def _endor_go_library_get_callgraph_metadata(target, ctx):
-    """Extract callgraph metadata from Go library targets using gopackagesdriver and VTA."""
+    """Extract callgraph metadata from Go library targets using export data and VTA."""
     if not hasattr(target, "files") and not hasattr(ctx, "attr"):
         return [OutputGroupInfo(endor_callgraph_info = depset([]))]
 
@@ -101,57 +101,36 @@ def _endor_go_library_get_callgraph_metadata(target, ctx):
     # Create callgraph analysis output file
     callgraph_json = ctx.actions.declare_file("callgraph_{}.json".format(compute_package_version_name(str(ctx.label))))
     
-    # Get the Go source files for this target
+    # Get the Go source files for this target; without any, the tool writes
+    # an empty result
     go_sources = []
     if hasattr(ctx.rule.files, "srcs"):
         go_sources = [f for f in ctx.rule.files.srcs if f.path.endswith(".go")]
-    
-    if not go_sources:
-        # No Go source files to analyze
-        ctx.actions.write(
-            output = callgraph_json,
-            content = '{"target": "' + str(ctx.label) + '", "import_path": "' + import_path + '", "callgraph": [], "error": "No Go source files found"}',
-        )
-        return [OutputGroupInfo(endor_callgraph_info = depset([callgraph_json]))]
-    
-    # Use the direct gopackagesdriver test
+
+    # Create packages JSON file describing the library sources and the
+    # export files of its dependencies
+    packages_json_file = ctx.actions.declare_file("packages_{}.json".format(compute_package_version_name(str(ctx.label))))
+    packages_json, package_inputs = get_go_packages_json(ctx, target, import_path, go_sources)
+    ctx.actions.write(
+        output = packages_json_file,
+        content = packages_json,
+    )
+
+    # Run the callgraph tool, loading the library sources and the export data
+    # of its dependencies without running the go command
     args = ctx.actions.args()
-    args.add(callgraph_json.path)  # output_file
-    
-    # Set up environment with GOPACKAGESDRIVER pointing to Bazel's gopackagesdriver
-    env = {}
-    env.update(ctx.configuration.default_shell_env)
-    env["GOPACKAGESDRIVER"] = ctx.executable._gopackagesdriver.path
-    
-    # CRITICAL: gopackagesdriver needs access to the bazel binary
-    # Add common bazel locations to PATH so gopackagesdriver can find it
-    current_path = env.get("PATH", "")
-    additional_paths = ["/opt/homebrew/bin", "/usr/local/bin", "/usr/bin"]
-    for path in additional_paths:
-        if current_path:
-            current_path = path + ":" + current_path
-        else:
-            current_path = path
-    env["PATH"] = current_path
-    
-    # Add additional Bazel-specific environment variables that gopackagesdriver might need
-    # The gopackagesdriver needs to know the actual workspace directory, not just the name
-    env["BUILD_WORKSPACE_DIRECTORY"] = "."  # Current directory is the execroot
-    env["BAZEL_TARGET"] = str(ctx.label)
-    env["BUILD_WORKING_DIRECTORY"] = "."
-    
-    # Try to provide the Go module information that gopackagesdriver might need
-    env["GO111MODULE"] = "on"
-    
-    # CRITICAL: Bazelisk needs HOME directory to be set
-    env["HOME"] = "/tmp"  # Use a temporary directory for HOME in the sandbox
+    args.add("--loader=exportdata")
+    sdk_args, sdk_inputs = get_go_sdk_args(ctx, target)
+    args.add_all(sdk_args)
+    args.add(packages_json_file.path)
+    args.add(callgraph_json.path)
     
     ctx.actions.run(
         outputs = [callgraph_json],
-        inputs = go_sources + [ctx.executable._gopackagesdriver],
-        executable = ctx.executable._protobuf_analyzer,
+        inputs = depset([packages_json_file] + go_sources, transitive = [package_inputs, sdk_inputs]),
+        executable = ctx.executable._callgraph_tool,
         arguments = [args],
-        env = env,
+        env = ctx.configuration.default_shell_env,
     )
     
     return [OutputGroupInfo(endor_callgraph_info = depset([callgraph_json]))]
@@ -159,16 +138,12 @@ def _endor_go_library_get_callgraph_metadata(target, ctx):
 internal_endor_go_library_generate_callgraph_metadata = aspect(
     attr_aspects = ["deps"],
     implementation = _endor_go_library_get_callgraph_metadata,
+    toolchains = [GO_TOOLCHAIN_TYPE],
     attrs = {
         "ref": attr.string(default = ""),
         "target_name": attr.string(default = ""),
-        "_protobuf_analyzer": attr.label(
-            default = Label("//tools/callgraph:direct_gopackagesdriver_test"),
-            executable = True,
-            cfg = "exec",
-        ),
-        "_gopackagesdriver": attr.label(
-            default = Label("@rules_go//go/tools/gopackagesdriver"),
+        "_callgraph_tool": attr.label(
+            default = Label("//aspects/golang/common/callgraph"),
             executable = True,
             cfg = "exec",
         ),
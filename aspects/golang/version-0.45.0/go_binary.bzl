@@ -1,7 +1,8 @@
 """Go binary dependency analysis aspects."""
 
-load("//aspects/golang/common:utils.bzl", "compute_package_version_name", "get_go_dependency_labels", "get_go_name_version_and_import_path")
+load("//aspects/golang/common:utils.bzl", "GO_TOOLCHAIN_TYPE", "SCHEMA_VERSION", "compute_package_version_name", "get_go_dependency_labels", "get_go_name_version_and_import_path", "get_go_packages_json")
 load("//aspects/golang/provider:endor_go_dependency_info.bzl", "EndorGoDependencyInfo")
+load(":go_library.bzl", "get_go_callgraph_summaries", "go_callgraph_summary", "internal_endor_go_library_generate_callgraph_metadata")
 
 def _endor_go_binary_resolve_dependencies(target, ctx):
     """Extract dependencies from Go binary targets and create JSON output."""
@@ -44,7 +45,7 @@ def _endor_go_binary_resolve_dependencies(target, ctx):
 
     ctx.actions.write(
         output = output_json,
-        content = "{\"nodes\": [" + json_content + "]}",
+        content = "{\"schema_version\": " + str(SCHEMA_VERSION) + ", \"nodes\": [" + json_content + "]}",
     )
 
     outputs_to_merge = [output_json]
@@ -232,6 +233,7 @@ def _generate_packages_json(target, ctx, source_files):
     
     # Create the response structure that VTA analyzer expects WITH STDLIB
     response = {
+        "schema_version": SCHEMA_VERSION,
         "NotHandled": False,
         "Compiler": "gc", 
         "Arch": "arm64",  # Could be detected from ctx if needed
@@ -242,161 +244,81 @@ def _generate_packages_json(target, ctx, source_files):
     # Convert to JSON string for embedding in shell script
     return json.encode(response)
 
-def _create_package_info_with_sources(ctx):
-    """Create package information for VTA analysis using workspace source files."""
-    
-    # Extract the main package information
-    name, version, import_path = get_go_name_version_and_import_path(ctx)
-    
-    # Get the actual package path from the Bazel label
-    package_path = ctx.label.package
-    
-    # Use the package path directly for the PkgPath (this is what packages.Load expects)
-    if not import_path:
-        import_path = package_path
-    
-    # Create package info that the VTA analyzer can use
-    # The VTA analyzer will handle the package loading itself
-    main_package = {
-        "ID": str(ctx.label),
-        "Name": name or "main",
-        "PkgPath": package_path,  # Use the Bazel package path directly
-        "GoFiles": [],  # Let the VTA analyzer discover the files
-        "CompiledGoFiles": [],
-        "Imports": {},
-        "ExportFile": "",
-    }
-    
-    # Collect all Go dependencies from the Bazel dependency graph
-    all_packages = [main_package]
-    
-    # Helper function to extract Go package info from a target
-    def extract_go_package_info(target, target_label):
-        if hasattr(target, "files"):
-            # Check if any files end with .go
-            has_go_files = False
-            for f in target.files.to_list():
-                if f.path.endswith(".go"):
-                    has_go_files = True
-                    break
-            
-            if has_go_files:
-                pkg_path = target_label.package
-                return {
-                    "ID": str(target_label),
-                    "Name": target_label.name,
-                    "PkgPath": pkg_path,
-                    "GoFiles": [],
-                    "CompiledGoFiles": [],
-                    "Imports": {},
-                    "ExportFile": "",
-                }
-        return None
-    
-    # Collect dependencies from embed attribute (go_library targets)
-    if hasattr(ctx.rule.attr, "embed"):
-        for embed_dep in ctx.rule.attr.embed:
-            # Add the embedded library itself
-            pkg_info = extract_go_package_info(embed_dep, embed_dep.label)
-            if pkg_info:
-                all_packages.append(pkg_info)
-            
-            # Also collect dependencies of the embedded library through the rule attribute
-            if hasattr(embed_dep, "attr") and hasattr(embed_dep.attr, "deps"):
-                for nested_dep in embed_dep.attr.deps:
-                    nested_pkg_info = extract_go_package_info(nested_dep, nested_dep.label)
-                    if nested_pkg_info:
-                        all_packages.append(nested_pkg_info)
-    
-    # Collect dependencies from direct deps attribute
-    if hasattr(ctx.rule.attr, "deps"):
-        for dep in ctx.rule.attr.deps:
-            pkg_info = extract_go_package_info(dep, dep.label)
-            if pkg_info:
-                all_packages.append(pkg_info)
-    
-    # TODO: Recursively collect transitive dependencies
-    # For now, we'll let the VTA analyzer discover packages through Go's module system
-    
-    # Create the response structure with dynamic values
-    # The VTA analyzer will detect architecture at runtime
-    response = {
-        "NotHandled": False,
-        "Compiler": "gc",
-        "Arch": "auto-detect",  # Will be detected by VTA analyzer
-        "Roots": [str(ctx.label)],
-        "Packages": all_packages
-    }
-    
-    return json.encode(response)
-
-# Removed hardcoded stdlib packages - VTA analyzer will discover them dynamically
 def _endor_go_binary_get_callgraph_metadata(target, ctx):
-    """Extract callgraph metadata from Go binary targets using VTA analyzer."""
-    if not hasattr(target, "files") and not hasattr(ctx, "attr"):
-        return [OutputGroupInfo(endor_callgraph_info = depset([]))]
+    """Link the call graph of Go binary targets from per-package summaries.
+
+    The summaries of the libraries come from the library callgraph aspect
+    this aspect requires, so each library is analysed once and its summary
+    is shared by every binary depending on it.
+    """
 
     # Only process go_binary targets
     if ctx.rule.kind != "go_binary":
-        return [OutputGroupInfo(endor_callgraph_info = depset([]))]
+        return []
 
     name, version, import_path = get_go_name_version_and_import_path(ctx)
+    summaries = get_go_callgraph_summaries(ctx)
     
+    # Summarize the binary's own sources; a binary embedding its main
+    # package has none
+    go_sources = []
+    if hasattr(ctx.rule.files, "srcs"):
+        go_sources = [f for f in ctx.rule.files.srcs if f.path.endswith(".go")]
+
+    root = str(ctx.label)
+    if go_sources:
+        packages_json_file = ctx.actions.declare_file("packages_{}.json".format(compute_package_version_name(str(ctx.label))))
+        packages_json, package_inputs = get_go_packages_json(ctx, target, import_path or ctx.label.package, go_sources)
+        ctx.actions.write(
+            output = packages_json_file,
+            content = packages_json,
+        )
+        summary_json = go_callgraph_summary(
+            ctx,
+            target,
+            packages_json_file,
+            depset(go_sources, transitive = [package_inputs]),
+            ctx.executable._callgraph_tool,
+        )
+        summaries = [depset([summary_json])] + summaries
+    elif getattr(ctx.rule.attr, "embed", []):
+        root = str(ctx.rule.attr.embed[0].label)
+
+    summary_files = depset(transitive = summaries)
+
     # Create callgraph analysis output file
     callgraph_json = ctx.actions.declare_file("callgraph_{}.json".format(compute_package_version_name(str(ctx.label))))
-    
-    # Create package information for VTA analysis with actual source files
-    # We construct paths to the workspace source files for VTA analysis
-    packages_json_content = _create_package_info_with_sources(ctx)
-    
-    # Create packages JSON file
-    packages_json_file = ctx.actions.declare_file("packages_{}.json".format(compute_package_version_name(str(ctx.label))))
-    ctx.actions.write(
-        output = packages_json_file,
-        content = packages_json_content,
-    )
-    
-    # Collect actual source files to pass as inputs
-    source_files = []
-    
-    # Get source files from embedded libraries
-    if hasattr(ctx.rule.attr, "embed"):
-        for embed_dep in ctx.rule.attr.embed:
-            if DefaultInfo in embed_dep:
-                embed_files = embed_dep[DefaultInfo].files.to_list()
-                go_files = [f for f in embed_files if f.path.endswith(".go")]
-                source_files.extend(go_files)
-    
-    # Get source files from direct srcs
-    if hasattr(ctx.rule.files, "srcs"):
-        target_sources = [f for f in ctx.rule.files.srcs if f.path.endswith(".go")]
-        source_files.extend(target_sources)
-    
-    # Run VTA analyzer tool with actual source files as inputs
+
+    # Link the summaries, resolving the interface and closure calls left in
+    # each package against the whole program
     args = ctx.actions.args()
-    args.add(packages_json_file.path)
+    args.add("link")
+    args.add("--root=" + root)
     args.add(callgraph_json.path)
+    args.add_all(summary_files)
     
     ctx.actions.run(
         outputs = [callgraph_json],
-        inputs = [packages_json_file] + source_files,
-        executable = ctx.executable._vta_analyzer_tool,
+        inputs = summary_files,
+        executable = ctx.executable._callgraph_tool,
         arguments = [args],
         use_default_shell_env = True,
-        mnemonic = "VTACallGraphAnalysis",
-        progress_message = "Analyzing call graph for %s" % ctx.label,
+        mnemonic = "GoCallGraphLink",
+        progress_message = "Linking call graph for %s" % ctx.label,
     )
     
     return [OutputGroupInfo(endor_callgraph_info = depset([callgraph_json]))]
 
 internal_endor_go_binary_generate_callgraph_metadata = aspect(
-    attr_aspects = ["deps"],
+    attr_aspects = ["deps", "embed"],
     implementation = _endor_go_binary_get_callgraph_metadata,
+    requires = [internal_endor_go_library_generate_callgraph_metadata],
+    toolchains = [GO_TOOLCHAIN_TYPE],
     attrs = {
         "ref": attr.string(default = ""),
         "target_name": attr.string(default = ""),
-        "_vta_analyzer_tool": attr.label(
-            default = Label("//aspects/golang/common:vta_analyzer_simple"),
+        "_callgraph_tool": attr.label(
+            default = Label("//aspects/golang/common/callgraph"),
             executable = True,
             cfg = "exec",
         ),
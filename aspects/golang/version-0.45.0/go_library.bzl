@@ -1,6 +1,7 @@
 """Go library dependency analysis aspects."""
 
-load("//aspects/golang/common:utils.bzl", "compute_package_version_name", "get_go_dependency_labels", "get_go_name_version_and_import_path")
+load("//aspects/golang/common:utils.bzl", "GO_TOOLCHAIN_TYPE", "SCHEMA_VERSION", "compute_package_version_name", "get_go_dependency_labels", "get_go_name_version_and_import_path", "get_go_packages_json", "get_go_sdk_args")
+load("//aspects/golang/provider:endor_go_callgraph_summary_info.bzl", "EndorGoCallgraphSummaryInfo")
 load("//aspects/golang/provider:endor_go_dependency_info.bzl", "EndorGoDependencyInfo")
 
 def _endor_go_library_resolve_dependencies(target, ctx):
@@ -44,7 +45,7 @@ def _endor_go_library_resolve_dependencies(target, ctx):
 
     ctx.actions.write(
         output = output_json,
-        content = "{\"nodes\": [" + json_content + "]}",
+        content = "{\"schema_version\": " + str(SCHEMA_VERSION) + ", \"nodes\": [" + json_content + "]}",
     )
 
     outputs_to_merge = [output_json]
@@ -81,75 +82,122 @@ internal_endor_go_library_resolve_dependencies = aspect(
     },
 )
 
+def get_go_callgraph_summaries(ctx):
+    """Collect the call graph summaries of the dependencies of a Go target."""
+    summaries = []
+    for attr_name in ["deps", "embed"]:
+        for dep in getattr(ctx.rule.attr, attr_name, []):
+            if EndorGoCallgraphSummaryInfo in dep:
+                summaries.append(dep[EndorGoCallgraphSummaryInfo].summaries)
+    return summaries
+
+def go_callgraph_summary(ctx, target, packages_json_file, package_inputs, callgraph_tool, result_json = None):
+    """Summarize the call graph of the package described by a packages JSON file.
+
+    The summary holds the functions and resolved calls of the package and the
+    interface and closure calls left for the binary to resolve when linking.
+    package_inputs are the sources and export files the packages JSON names;
+    the GoArchive of target tells whether the package is built with cgo.
+    When result_json is given, the same action also writes the call graph
+    result of the package, linked from its summary, so the package is only
+    analyzed once.
+    """
+    summary_json = ctx.actions.declare_file("callgraph_summary_{}.json".format(compute_package_version_name(str(ctx.label))))
+    outputs = [summary_json]
+
+    args = ctx.actions.args()
+    args.add("summarize")
+    args.add("--loader=exportdata")
+    sdk_args, sdk_inputs = get_go_sdk_args(ctx, target)
+    args.add_all(sdk_args)
+    if result_json:
+        args.add("--result=" + result_json.path)
+        outputs.append(result_json)
+    args.add(packages_json_file.path)
+    args.add(summary_json.path)
+
+    ctx.actions.run(
+        outputs = outputs,
+        inputs = depset([packages_json_file], transitive = [package_inputs, sdk_inputs]),
+        executable = callgraph_tool,
+        arguments = [args],
+        env = ctx.configuration.default_shell_env,
+        mnemonic = "GoCallGraphSummary",
+        progress_message = "Summarizing call graph of %s" % ctx.label,
+    )
+
+    return summary_json
+
 def _endor_go_library_get_callgraph_metadata(target, ctx):
-    """Extract callgraph metadata from Go library targets using gopackagesdriver and VTA."""
-    if not hasattr(target, "files") and not hasattr(ctx, "attr"):
-        return [OutputGroupInfo(endor_callgraph_info = depset([]))]
+    """Extract callgraph metadata from Go library targets using export data.
 
-    # Only process go_library targets
+    Each library summarizes its call graph, in the same action that writes
+    its call graph result, and the summaries of its transitive dependencies
+    are propagated for binaries to link.
+    """
+    dep_summaries = get_go_callgraph_summaries(ctx)
+
+    # Only process go_library targets; others pass their dependencies'
+    # summaries through
     if ctx.rule.kind != "go_library":
-        return [OutputGroupInfo(endor_callgraph_info = depset([]))]
+        return [EndorGoCallgraphSummaryInfo(summaries = depset(transitive = dep_summaries))]
 
     name, version, import_path = get_go_name_version_and_import_path(ctx)
     
     if not import_path:
         # Skip targets without import paths
-        return [OutputGroupInfo(endor_callgraph_info = depset([]))]
+        return [
+            EndorGoCallgraphSummaryInfo(summaries = depset(transitive = dep_summaries)),
+            OutputGroupInfo(endor_callgraph_info = depset([])),
+        ]
     
     # Create callgraph analysis output file
     callgraph_json = ctx.actions.declare_file("callgraph_{}.json".format(compute_package_version_name(str(ctx.label))))
     
-    # Get the Go source files for this target
+    # Get the Go source files for this target; without any, the tool writes
+    # an empty summary and result
     go_sources = []
     if hasattr(ctx.rule.files, "srcs"):
         go_sources = [f for f in ctx.rule.files.srcs if f.path.endswith(".go")]
-    
-    if not go_sources:
-        # No Go source files to analyze
-        ctx.actions.write(
-            output = callgraph_json,
-            content = '{"target": "' + str(ctx.label) + '", "import_path": "' + import_path + '", "callgraph": [], "error": "No Go source files found"}',
-        )
-        return [OutputGroupInfo(endor_callgraph_info = depset([callgraph_json]))]
-    
-    # Use the callgraph analyzer with VTA and packages.Load
-    args = ctx.actions.args()
-    args.add(str(ctx.label))  # target
-    args.add(import_path)     # import_path
-    args.add(callgraph_json.path)  # output_file
-    
-    # Add all source files as arguments
-    for src_file in go_sources:
-        args.add(src_file.path)
-    
-    # Set up environment with GOPACKAGESDRIVER pointing to Bazel's gopackagesdriver
-    env = {}
-    env.update(ctx.configuration.default_shell_env)
-    env["GOPACKAGESDRIVER"] = ctx.executable._gopackagesdriver.path
-    
-    ctx.actions.run(
-        outputs = [callgraph_json],
-        inputs = go_sources + [ctx.executable._gopackagesdriver],
-        executable = ctx.executable._protobuf_analyzer,
-        arguments = [args],
-        env = env,
+
+    # Create packages JSON file describing the library sources and the
+    # export files of its dependencies
+    packages_json_file = ctx.actions.declare_file("packages_{}.json".format(compute_package_version_name(str(ctx.label))))
+    packages_json, package_inputs = get_go_packages_json(ctx, target, import_path, go_sources)
+    ctx.actions.write(
+        output = packages_json_file,
+        content = packages_json,
+    )
+
+    # Summarize the library, loading its sources and the export data of its
+    # dependencies without running the go command, and link its call graph
+    # result from the summary
+    summary_json = go_callgraph_summary(
+        ctx,
+        target,
+        packages_json_file,
+        depset(go_sources, transitive = [package_inputs]),
+        ctx.executable._callgraph_tool,
+        result_json = callgraph_json,
     )
     
-    return [OutputGroupInfo(endor_callgraph_info = depset([callgraph_json]))]
+    return [
+        EndorGoCallgraphSummaryInfo(summaries = depset([summary_json], transitive = dep_summaries)),
+        OutputGroupInfo(
+            endor_callgraph_info = depset([callgraph_json]),
+            endor_callgraph_summary = depset([summary_json]),
+        ),
+    ]
 
 internal_endor_go_library_generate_callgraph_metadata = aspect(
-    attr_aspects = ["deps"],
+    attr_aspects = ["deps", "embed"],
     implementation = _endor_go_library_get_callgraph_metadata,
+    toolchains = [GO_TOOLCHAIN_TYPE],
     attrs = {
         "ref": attr.string(),
         "target_name": attr.string(),
-        "_protobuf_analyzer": attr.label(
-            default = Label("//tools/callgraph:callgraph"),
-            executable = True,
-            cfg = "exec",
-        ),
-        "_gopackagesdriver": attr.label(
-            default = Label("@rules_go//go/tools/gopackagesdriver"),
+        "_callgraph_tool": attr.label(
+            default = Label("//aspects/golang/common/callgraph"),
             executable = True,
             cfg = "exec",
         ),